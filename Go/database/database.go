@@ -8,6 +8,10 @@ import (
 	migrate "github.com/rubenv/sql-migrate"
 )
 
+// Migrations run in file name order after initial.sql. Name new files
+// vNNN_description.sql: sql-migrate runs purely numeric prefixes before
+// anything else, so they would be applied ahead of the initial schema.
+//
 //go:embed migrations/*.sql
 var dbMigrations embed.FS
 var DbConnection *sql.DB
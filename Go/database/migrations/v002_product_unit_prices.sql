@@ -0,0 +1,27 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Add unit values and discounts to product lines
+ALTER TABLE products
+    ADD COLUMN unit_cost NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (unit_cost >= 0),                    -- Cost per unit (type: number, minValue: 0)
+    ADD COLUMN unit_price NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (unit_price >= 0),                  -- Price per unit before discount (type: number, minValue: 0)
+    ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (discount_amount >= 0),        -- Discount taken off the line (type: number, minValue: 0)
+    ADD COLUMN discount_percent NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100); -- Discount as a percentage of quantity × unit_price
+
+-- Backfill unit values for lines created before this migration (no discounts were recorded)
+UPDATE products
+SET unit_cost = ROUND(total_cost / quantity, 2),
+    unit_price = ROUND(total_price / quantity, 2);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE products
+    DROP COLUMN unit_cost,
+    DROP COLUMN unit_price,
+    DROP COLUMN discount_amount,
+    DROP COLUMN discount_percent;
+
+-- +migrate StatementEnd
@@ -0,0 +1,43 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table tax_rates
+CREATE TABLE tax_rates (
+    code TEXT PRIMARY KEY CHECK (LENGTH(code) >= 1),                -- Tax code referenced by product lines (required: true, type: text)
+    name TEXT NOT NULL CHECK (LENGTH(name) >= 2),                   -- Display name (required: true, type: text, minLength: 2)
+    rate NUMERIC(5, 2) NOT NULL CHECK (rate BETWEEN 0 AND 100),      -- Rate in percent (required: true, type: number, 0-100)
+    active BOOLEAN NOT NULL DEFAULT TRUE                            -- Inactive codes cannot be used on new invoices
+);
+
+INSERT INTO tax_rates (code, name, rate) VALUES
+    ('PPN', 'Pajak Pertambahan Nilai', 11.00),
+    ('EXEMPT', 'Tax exempt', 0.00);
+
+-- Pricing mode and rolled-up tax on invoices
+ALTER TABLE invoices
+    ADD COLUMN tax_mode TEXT NOT NULL DEFAULT 'exclusive' CHECK (tax_mode IN ('exclusive', 'inclusive')), -- Whether total_price excludes or includes tax
+    ADD COLUMN tax_total NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (tax_total >= 0);                       -- Sum of tax_amount over the invoice's products
+
+-- Tax per product line; the rate is copied so later rate changes don't rewrite history
+ALTER TABLE products
+    ADD COLUMN tax_code TEXT REFERENCES tax_rates(code),                                -- Tax code (optional, type: text)
+    ADD COLUMN tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (tax_rate BETWEEN 0 AND 100), -- Rate applied when the line was created
+    ADD COLUMN tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0);       -- Tax computed for the line
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE products
+    DROP COLUMN tax_code,
+    DROP COLUMN tax_rate,
+    DROP COLUMN tax_amount;
+
+ALTER TABLE invoices
+    DROP COLUMN tax_mode,
+    DROP COLUMN tax_total;
+
+DROP TABLE tax_rates;
+
+-- +migrate StatementEnd
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Currency of each invoice; invoices created before this migration are assumed to be in IDR,
+-- the default base currency (v013 drops the default)
+ALTER TABLE invoices
+    ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR' CHECK (currency ~ '^[A-Z]{3}$'); -- ISO 4217 code (required: true, type: text, length: 3)
+
+-- Create table exchange_rates
+CREATE TABLE exchange_rates (
+    id SERIAL PRIMARY KEY,                                                 -- Auto-incremented unique identifier
+    base_currency TEXT NOT NULL CHECK (base_currency ~ '^[A-Z]{3}$'),      -- Currency the rate converts into
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),                -- Currency the rate converts from
+    rate_date DATE NOT NULL,                                               -- First day the rate applies
+    rate NUMERIC(18, 6) NOT NULL CHECK (rate > 0),                         -- Base currency units per unit of currency
+    CONSTRAINT uq_exchange_rates_day UNIQUE (base_currency, currency, rate_date),
+    CONSTRAINT chk_exchange_rates_pair CHECK (base_currency <> currency)
+);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE exchange_rates;
+
+ALTER TABLE invoices
+    DROP COLUMN currency;
+
+-- +migrate StatementEnd
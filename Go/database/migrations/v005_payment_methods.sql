@@ -0,0 +1,40 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table payment_methods, replacing payment_type_enum
+CREATE TABLE payment_methods (
+    code TEXT PRIMARY KEY CHECK (code ~ '^[A-Z][A-Z0-9_-]*$'),   -- Code stored on invoices (required: true, type: text, upper case)
+    name TEXT NOT NULL CHECK (LENGTH(name) >= 2),                -- Display name (required: true, type: text, minLength: 2)
+    active BOOLEAN NOT NULL DEFAULT TRUE                         -- Inactive methods cannot be used on new invoices
+);
+
+INSERT INTO payment_methods (code, name) VALUES
+    ('CASH', 'Cash'),
+    ('CREDIT', 'Credit');
+
+-- Convert the ENUM column in place; every existing value has a matching row above
+ALTER TABLE invoices
+    ALTER COLUMN payment_type TYPE TEXT USING payment_type::TEXT;
+
+ALTER TABLE invoices
+    ADD CONSTRAINT fk_invoices_payment_type FOREIGN KEY (payment_type) REFERENCES payment_methods(code) ON UPDATE CASCADE;
+
+DROP TYPE payment_type_enum;
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+-- Fails if invoices use a method other than CASH or CREDIT
+CREATE TYPE payment_type_enum AS ENUM ('CASH', 'CREDIT');
+
+ALTER TABLE invoices
+    DROP CONSTRAINT fk_invoices_payment_type;
+
+ALTER TABLE invoices
+    ALTER COLUMN payment_type TYPE payment_type_enum USING payment_type::payment_type_enum;
+
+DROP TABLE payment_methods;
+
+-- +migrate StatementEnd
@@ -0,0 +1,57 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Credit methods leave the invoice open until payments settle it
+ALTER TABLE payment_methods
+    ADD COLUMN is_credit BOOLEAN NOT NULL DEFAULT FALSE; -- Whether invoices using this method are paid later
+
+UPDATE payment_methods SET is_credit = TRUE WHERE code = 'CREDIT';
+
+-- Due date, payment terms and grand total of invoices
+ALTER TABLE invoices
+    ADD COLUMN due_date DATE,                                                          -- Date a credit invoice must be paid by (optional, type: date)
+    ADD COLUMN payment_terms_days INT CHECK (payment_terms_days >= 0),                  -- Days between date and due_date (optional, type: number, minValue: 0)
+    ADD COLUMN total_amount NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (total_amount >= 0), -- Amount the customer owes, including exclusive tax
+    ADD CONSTRAINT chk_due_date CHECK (due_date >= date);
+
+UPDATE invoices i
+SET total_amount = COALESCE((SELECT SUM(p.total_price) FROM products p WHERE p.invoice_no = i.invoice_no), 0)
+                 + CASE WHEN i.tax_mode = 'exclusive' THEN i.tax_total ELSE 0 END;
+
+-- Existing credit invoices get the default 30-day terms
+UPDATE invoices
+SET payment_terms_days = 30,
+    due_date = date + 30
+WHERE payment_type IN (SELECT code FROM payment_methods WHERE is_credit);
+
+-- Create table payments
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,                                                        -- Auto-incremented unique identifier
+    invoice_no TEXT NOT NULL REFERENCES invoices(invoice_no) ON DELETE CASCADE,   -- Invoice being paid (required: true, type: text)
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),                            -- Amount received (required: true, type: number, minValue: 0.01)
+    paid_at DATE NOT NULL,                                                        -- Date the payment was received (required: true, type: date)
+    payment_method TEXT REFERENCES payment_methods(code) ON UPDATE CASCADE,       -- How it was paid, e.g. TRANSFER (optional, type: text)
+    reference TEXT,                                                               -- Bank or receipt reference (optional, type: text)
+    notes TEXT,                                                                   -- Notes for additional information (optional, type: text)
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()                                 -- When the payment was recorded
+);
+
+CREATE INDEX idx_payments_invoice_no ON payments (invoice_no);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE payments;
+
+ALTER TABLE invoices
+    DROP CONSTRAINT chk_due_date,
+    DROP COLUMN due_date,
+    DROP COLUMN payment_terms_days,
+    DROP COLUMN total_amount;
+
+ALTER TABLE payment_methods
+    DROP COLUMN is_credit;
+
+-- +migrate StatementEnd
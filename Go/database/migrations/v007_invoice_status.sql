@@ -0,0 +1,29 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Workflow status of invoices; invoices created before this migration were final, so they start as issued
+ALTER TABLE invoices
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'issued' CHECK (status IN ('draft', 'issued', 'void')), -- draft -> issued -> void
+    ADD COLUMN issued_at TIMESTAMPTZ,                                                                -- When the invoice was issued
+    ADD COLUMN voided_at TIMESTAMPTZ,                                                                -- When the invoice was voided
+    ADD COLUMN void_reason TEXT,                                                                     -- Why the invoice was voided
+    ADD CONSTRAINT chk_void_reason CHECK (status <> 'void' OR LENGTH(void_reason) >= 5);             -- Voiding requires a reason of at least 5 characters
+
+UPDATE invoices SET issued_at = date;
+
+ALTER TABLE invoices
+    ALTER COLUMN status SET DEFAULT 'draft';
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE invoices
+    DROP CONSTRAINT chk_void_reason,
+    DROP COLUMN status,
+    DROP COLUMN issued_at,
+    DROP COLUMN voided_at,
+    DROP COLUMN void_reason;
+
+-- +migrate StatementEnd
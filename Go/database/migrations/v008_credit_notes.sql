@@ -0,0 +1,40 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table credit_notes
+CREATE TABLE credit_notes (
+    id SERIAL PRIMARY KEY,                                                        -- Auto-incremented unique identifier
+    credit_note_no TEXT NOT NULL UNIQUE,                                          -- Credit note number (required: true, type: text, unique)
+    invoice_no TEXT NOT NULL REFERENCES invoices(invoice_no),                     -- Original invoice being credited (required: true, type: text)
+    date DATE NOT NULL,                                                           -- Date of the credit note (required: true, type: date)
+    reason TEXT NOT NULL CHECK (LENGTH(reason) >= 5),                             -- Why the goods were returned (required: true, type: text, minLength: 5)
+    tax_total NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (tax_total >= 0),            -- Tax refunded across the lines
+    total_amount NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (total_amount >= 0),      -- Amount credited to the customer, including exclusive tax
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()                                 -- When the credit note was recorded
+);
+
+CREATE INDEX idx_credit_notes_invoice_no ON credit_notes (invoice_no);
+CREATE INDEX idx_credit_notes_date ON credit_notes (date);
+
+-- Create table credit_note_lines
+CREATE TABLE credit_note_lines (
+    id SERIAL PRIMARY KEY,                                                             -- Auto-incremented unique identifier
+    credit_note_id INT NOT NULL REFERENCES credit_notes(id) ON DELETE CASCADE,         -- Credit note the line belongs to (required: true, type: number)
+    product_id INT NOT NULL REFERENCES products(id),                                   -- Product line of the original invoice (required: true, type: number)
+    quantity INT NOT NULL CHECK (quantity >= 1),                                       -- Quantity returned (required: true, type: number, minValue: 1)
+    total_cost NUMERIC(12, 2) NOT NULL CHECK (total_cost >= 0),                        -- Cost of the returned goods
+    total_price NUMERIC(12, 2) NOT NULL CHECK (total_price >= 0),                      -- Price credited for the returned goods, after discount
+    tax_amount NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0)               -- Tax refunded on total_price
+);
+
+CREATE INDEX idx_credit_note_lines_product_id ON credit_note_lines (product_id);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE credit_note_lines;
+DROP TABLE credit_notes;
+
+-- +migrate StatementEnd
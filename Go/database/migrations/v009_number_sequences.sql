@@ -0,0 +1,29 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table number_sequences
+CREATE TABLE number_sequences (
+    name TEXT PRIMARY KEY,                       -- What the sequence numbers, e.g. invoice (required: true, type: text)
+    pattern TEXT NOT NULL,                       -- Pattern such as INV-{YYYY}{MM}-{SEQ:5} (required: true, type: text)
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- When the pattern was last changed
+);
+
+INSERT INTO number_sequences (name, pattern) VALUES ('invoice', 'INV-{YYYY}{MM}-{SEQ:5}');
+
+-- Create table number_sequence_counters
+CREATE TABLE number_sequence_counters (
+    name TEXT NOT NULL REFERENCES number_sequences(name) ON DELETE CASCADE, -- Sequence the counter belongs to (required: true, type: text)
+    period TEXT NOT NULL,                                                    -- Period the counter restarts on, e.g. 2025-01 ('' if it never restarts)
+    last_value BIGINT NOT NULL CHECK (last_value >= 1),                      -- Last number handed out in the period
+    PRIMARY KEY (name, period)
+);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE number_sequence_counters;
+DROP TABLE number_sequences;
+
+-- +migrate StatementEnd
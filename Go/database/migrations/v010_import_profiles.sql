@@ -0,0 +1,23 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table import_profiles
+CREATE TABLE import_profiles (
+    name TEXT PRIMARY KEY,                                                         -- Profile name used in ?profile=, e.g. distributor-a (required: true, type: text)
+    invoice_sheet TEXT NOT NULL DEFAULT '',                                        -- Name of the invoice sheet ('' for the default names)
+    product_sheet TEXT NOT NULL DEFAULT '',                                        -- Name of the product sheet ('' for the default names)
+    columns JSONB NOT NULL DEFAULT '{}',                                           -- Header of each field in the file, e.g. {"customer_name": "Buyer"}
+    date_format TEXT NOT NULL DEFAULT '',                                          -- Date format such as DD/MM/YYYY ('' for DD-MM-YY)
+    decimal_separator TEXT NOT NULL DEFAULT '.' CHECK (decimal_separator IN ('.', ',')), -- Decimal separator of numbers
+    defaults JSONB NOT NULL DEFAULT '{}',                                          -- Value of each field when its cell is empty, e.g. {"payment_type": "CREDIT"}
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()                                  -- When the profile was last changed
+);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE import_profiles;
+
+-- +migrate StatementEnd
@@ -0,0 +1,43 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table import_jobs
+CREATE TABLE import_jobs (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'completed', 'failed', 'cancelled')), -- Where the job is in its lifecycle
+    format TEXT NOT NULL,                              -- Format of the upload: xlsx or csv
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,            -- Import options, as on the synchronous endpoint
+    atomic BOOLEAN NOT NULL DEFAULT FALSE,
+    profile TEXT NOT NULL DEFAULT '',                  -- Import profile applied ('' for none)
+    processed INT NOT NULL DEFAULT 0,                  -- Invoice rows processed so far
+    failed INT NOT NULL DEFAULT 0,                     -- Invoice rows rejected so far
+    errors JSONB NOT NULL DEFAULT '[]',                -- Errors of the finished job
+    result JSONB,                                      -- Result of the finished import
+    error TEXT NOT NULL DEFAULT '',                    -- Why the job failed
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,   -- Set by a cancel, checked by the running import
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_import_jobs_queued ON import_jobs (id) WHERE status = 'queued';
+
+-- Create table import_job_files
+CREATE TABLE import_job_files (
+    job_id INT NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE, -- Job the file was uploaded for
+    field TEXT NOT NULL,                                              -- Form field of the upload: file, invoices or products
+    name TEXT NOT NULL,                                               -- Name of the uploaded file
+    content BYTEA NOT NULL,                                           -- The uploaded file
+    PRIMARY KEY (job_id, field)
+);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE import_job_files;
+DROP TABLE import_jobs;
+
+-- +migrate StatementEnd
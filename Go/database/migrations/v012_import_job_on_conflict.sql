@@ -0,0 +1,17 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- How an import job handles invoice numbers that already exist
+ALTER TABLE import_jobs
+    ADD COLUMN on_conflict TEXT NOT NULL DEFAULT 'error'
+        CHECK (on_conflict IN ('error', 'skip', 'update', 'replace')); -- Import option, as on the synchronous endpoint
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE import_jobs
+    DROP COLUMN on_conflict;
+
+-- +migrate StatementEnd
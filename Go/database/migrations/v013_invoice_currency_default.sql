@@ -0,0 +1,17 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The application always sets the currency of an invoice, from BASE_CURRENCY when omitted,
+-- so the column no longer defaults to the base currency v004 assumed
+ALTER TABLE invoices
+    ALTER COLUMN currency DROP DEFAULT;
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE invoices
+    ALTER COLUMN currency SET DEFAULT 'IDR';
+
+-- +migrate StatementEnd
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table import_staging_rows: the rows of the files being imported, staged so an import pages
+-- through them instead of holding a file in memory. Each import stages its rows under an ID of its own
+-- and deletes them when it ends. The rows are only ever needed during the import, so the table is not
+-- written to the WAL.
+CREATE SEQUENCE import_staging_seq;
+
+CREATE UNLOGGED TABLE import_staging_rows (
+    import_id BIGINT NOT NULL,                                     -- Import the row was staged by
+    sheet TEXT NOT NULL CHECK (sheet IN ('invoices', 'products')), -- Import sheet the row is read as
+    row_no INT NOT NULL,                                           -- 1-based row number in the file
+    invoice_no TEXT NOT NULL DEFAULT '',                           -- Invoice number of the row ('' for none)
+    cells TEXT[] NOT NULL,                                         -- Values as displayed
+    raw TEXT[],                                                    -- Values as stored (NULL for CSV files)
+    PRIMARY KEY (import_id, sheet, row_no)
+);
+
+CREATE INDEX idx_import_staging_rows_invoice ON import_staging_rows (import_id, sheet, invoice_no, row_no);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE import_staging_rows;
+DROP SEQUENCE import_staging_seq;
+
+-- +migrate StatementEnd
@@ -0,0 +1,35 @@
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Create table import_instances: the servers sharing the database, with when each last showed it is
+-- running, so a server only cleans up after its own previous run and after the servers that stopped.
+CREATE TABLE import_instances (
+    id TEXT PRIMARY KEY,                            -- INSTANCE_ID of the server
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- Last time the server showed it is running
+);
+
+-- The server running each job and importing each staged file ('' for none, or from before servers
+-- were tracked)
+ALTER TABLE import_jobs
+    ADD COLUMN owner TEXT NOT NULL DEFAULT ''; -- Server the job was claimed by
+
+ALTER TABLE import_staging_rows
+    ADD COLUMN owner TEXT NOT NULL DEFAULT ''; -- Server importing the file
+
+CREATE INDEX idx_import_jobs_running ON import_jobs (owner) WHERE status = 'running';
+CREATE INDEX idx_import_staging_rows_owner ON import_staging_rows (owner);
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX idx_import_staging_rows_owner;
+DROP INDEX idx_import_jobs_running;
+ALTER TABLE import_staging_rows
+    DROP COLUMN owner;
+ALTER TABLE import_jobs
+    DROP COLUMN owner;
+DROP TABLE import_instances;
+
+-- +migrate StatementEnd
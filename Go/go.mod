@@ -8,6 +8,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/rubenv/sql-migrate v1.7.1
 	github.com/xuri/excelize/v2 v2.9.0
+	golang.org/x/text v0.21.0
 )
 
 require (
@@ -40,7 +41,6 @@ require (
 	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
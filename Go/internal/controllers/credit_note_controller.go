@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreditNoteController defines the controller layer for credit notes on returned goods
+type CreditNoteController struct {
+	CreditNoteService *service.CreditNoteService
+}
+
+// NewCreditNoteController creates a new CreditNoteController instance
+func NewCreditNoteController(creditNoteService *service.CreditNoteService) *CreditNoteController {
+	return &CreditNoteController{CreditNoteService: creditNoteService}
+}
+
+// GetCreditNotes lists the credit notes raised against an invoice
+func (cc *CreditNoteController) GetCreditNotes(ctx *gin.Context) {
+	creditNotes, err := cc.CreditNoteService.GetCreditNotes(ctx.Param("invoiceno"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve credit notes"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"credit_notes": creditNotes})
+}
+
+// CreateCreditNote records goods returned against an issued invoice
+func (cc *CreditNoteController) CreateCreditNote(ctx *gin.Context) {
+	var payload models.CreditNoteRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	creditNote := models.CreditNote{
+		CreditNoteNo: payload.CreditNoteNo,
+		InvoiceNo:    ctx.Param("invoiceno"),
+		Date:         payload.Date,
+		Reason:       payload.Reason,
+	}
+	for _, line := range payload.Lines {
+		creditNote.Lines = append(creditNote.Lines, models.CreditNoteLine{ProductID: line.ProductID, Quantity: line.Quantity})
+	}
+
+	err := cc.CreditNoteService.CreateCreditNote(&creditNote)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	case errors.Is(err, repository.ErrCreditNoteExists):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrInvalidCreditNote):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create credit note"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Credit note created successfully", "credit_note": creditNote})
+}
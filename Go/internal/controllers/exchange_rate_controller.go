@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateController defines the controller layer for exchange rate operations
+type ExchangeRateController struct {
+	ExchangeRateService *service.ExchangeRateService
+}
+
+// NewExchangeRateController creates a new ExchangeRateController instance
+func NewExchangeRateController(exchangeRateService *service.ExchangeRateService) *ExchangeRateController {
+	return &ExchangeRateController{ExchangeRateService: exchangeRateService}
+}
+
+// GetExchangeRates lists the exchange rates, optionally filtered by ?currency=
+func (ec *ExchangeRateController) GetExchangeRates(ctx *gin.Context) {
+	exchangeRates, err := ec.ExchangeRateService.GetExchangeRates(ctx.Query("currency"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"base_currency": utils.BaseCurrency(), "exchange_rates": exchangeRates})
+}
+
+// GetExchangeRate retrieves a single exchange rate by ID
+func (ec *ExchangeRateController) GetExchangeRate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	exchangeRate, err := ec.ExchangeRateService.GetExchangeRate(id)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"exchange_rate": exchangeRate})
+}
+
+// LookupExchangeRate returns the rate that applies to a currency on a date
+func (ec *ExchangeRateController) LookupExchangeRate(ctx *gin.Context) {
+	var payload models.ExchangeRateLookupRequest
+	if err := ctx.ShouldBindQuery(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "currency and date (YYYY-MM-DD) are required"})
+		return
+	}
+
+	exchangeRate, err := ec.ExchangeRateService.LookupExchangeRate(payload.Currency, payload.Date)
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up exchange rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"exchange_rate": exchangeRate})
+}
+
+// CreateExchangeRate handles the creation of a new exchange rate
+func (ec *ExchangeRateController) CreateExchangeRate(ctx *gin.Context) {
+	var payload models.ExchangeRateRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	exchangeRate := exchangeRateFromRequest(payload)
+	if err := utils.ValidateExchangeRate(exchangeRate); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ec.ExchangeRateService.CreateExchangeRate(&exchangeRate); err != nil {
+		if err == repository.ErrExchangeRateExists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Exchange rate created successfully", "exchange_rate": exchangeRate})
+}
+
+// UpdateExchangeRate updates the exchange rate identified by the ID in the URL
+func (ec *ExchangeRateController) UpdateExchangeRate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	var payload models.ExchangeRateRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	exchangeRate := exchangeRateFromRequest(payload)
+	exchangeRate.ID = id
+	if err := utils.ValidateExchangeRate(exchangeRate); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ec.ExchangeRateService.UpdateExchangeRate(exchangeRate); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+			return
+		}
+		if err == repository.ErrExchangeRateExists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Exchange rate updated successfully", "exchange_rate": exchangeRate})
+}
+
+// DeleteExchangeRate deletes an exchange rate by ID
+func (ec *ExchangeRateController) DeleteExchangeRate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	if err := ec.ExchangeRateService.DeleteExchangeRate(id); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// exchangeRateFromRequest maps a request body to an exchange rate into the base currency.
+func exchangeRateFromRequest(payload models.ExchangeRateRequest) models.ExchangeRate {
+	return models.ExchangeRate{
+		BaseCurrency: utils.BaseCurrency(),
+		Currency:     payload.Currency,
+		RateDate:     payload.RateDate,
+		Rate:         payload.Rate,
+	}
+}
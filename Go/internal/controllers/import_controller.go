@@ -1,51 +1,223 @@
 package controllers
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"widatech-technical-challenge/internal/models"
 	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ImportController defines the controller layer for importing operations
 type ImportController struct {
-	ImportService *service.ImportService
+	ImportService    *service.ImportService
+	ImportJobService *service.ImportJobService
 }
 
 // NewImportController creates a new ImportController instance
-func NewImportController(importService *service.ImportService) *ImportController {
-	return &ImportController{ImportService: importService}
+func NewImportController(importService *service.ImportService, importJobService *service.ImportJobService) *ImportController {
+	return &ImportController{ImportService: importService, ImportJobService: importJobService}
 }
 
-// ImportInvoices handles the import of invoices and products from an XLSX file
+// ImportInvoices handles the import of invoices and products from an XLSX workbook or CSV files.
+// The format of a "file" upload is sniffed from its content: a workbook, or a single CSV file with a row
+// per product line. Alternatively, "invoices" and "products" upload one CSV file per sheet.
+// With atomic=true the file is imported in full or not at all.
+// With on_conflict=skip, update or replace an invoice number that already exists is not rejected.
+// With dry_run=true the file is only checked, and the response shows what an import would do.
+// With async=true the upload is queued as an import job, and the response is 202 with the job to poll.
+// With report=true a workbook with rows that weren't imported is answered with its error report instead of JSON.
 func (ic *ImportController) ImportInvoices(ctx *gin.Context) {
-	// Parse the XLSX file from the request
-	file, err := ctx.FormFile("file")
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+	var payload models.ImportRequest
+	if err := ctx.ShouldBindQuery(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "dry_run, atomic, async and report must be true or false, on_conflict one of error, skip, update or replace"})
 		return
 	}
 
-	// Open the uploaded file
-	f, err := file.Open()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+	var uploads []service.ImportUpload
+	if invoicesHeader, err := ctx.FormFile("invoices"); err == nil {
+		productsHeader, err := ctx.FormFile("products")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "products is required with invoices"})
+			return
+		}
+		invoices, ok := openUpload(ctx, "invoices", invoicesHeader, models.ImportFormatCSV)
+		if !ok {
+			return
+		}
+		defer invoices.Close()
+		products, ok := openUpload(ctx, "products", productsHeader, models.ImportFormatCSV)
+		if !ok {
+			return
+		}
+		defer products.Close()
+		uploads = []service.ImportUpload{invoices.ImportUpload, products.ImportUpload}
+	} else {
+		// Parse the file from the request
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+			return
+		}
+		file, ok := openUpload(ctx, "file", fileHeader, "")
+		if !ok {
+			return
+		}
+		defer file.Close()
+		uploads = []service.ImportUpload{file.ImportUpload}
+	}
+
+	// The error report is a copy of the uploaded workbook, so keep the upload for it
+	var workbook []byte
+	if payload.Report && !payload.Async {
+		if len(uploads) != 1 || uploads[0].Format != models.ImportFormatXLSX {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": service.ErrImportReportUnsupported.Error()})
+			return
+		}
+		var err error
+		if workbook, err = io.ReadAll(uploads[0]); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+		uploads[0].Reader = bytes.NewReader(workbook)
+	}
+
+	if payload.Async {
+		job, err := ic.ImportJobService.Submit(uploads, payload)
+		if !respondImportError(ctx, err) {
+			return
+		}
+		ctx.Header("Location", fmt.Sprintf("/api/import/jobs/%d", job.ID))
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "Import job queued", "job": job})
 		return
 	}
-	defer f.Close()
 
 	// Process the file using the service layer
-	errors, err := ic.ImportService.ProcessXLSXFile(f)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process file"})
+	result, err := ic.ImportService.Import(ctx.Request.Context(), uploads, payload)
+	if !respondImportError(ctx, err) {
+		return
+	}
+
+	status := http.StatusOK
+	if result.Rejected > 0 && !payload.DryRun {
+		status = http.StatusBadRequest
+	}
+	if workbook != nil && service.HasRowsToReport(result) {
+		report, err := ic.ImportService.ErrorReport(bytes.NewReader(workbook), result, payload)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build the error report"})
+			return
+		}
+		respondReport(ctx, status, service.ReportFileName(uploads[0].Name), report, result)
 		return
 	}
 
-	// Respond with any validation or processing errors
-	if len(errors) > 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errors})
+	if payload.DryRun {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Dry run completed, nothing was imported", "result": result})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "File imported successfully"})
+	// Respond with any validation or processing errors, and the warnings of the imported invoices
+	if result.Rejected > 0 && payload.Atomic {
+		ctx.JSON(status, gin.H{"error": "Atomic import rolled back, nothing was imported", "errors": result.Errors, "result": result})
+		return
+	}
+	if result.Rejected > 0 {
+		ctx.JSON(status, gin.H{"errors": result.Errors, "warnings": result.Warnings, "result": result})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "File imported successfully", "warnings": result.Warnings, "result": result})
+}
+
+// GetImportTemplate handles the download of an import workbook to fill in, laid out with ?profile=name if given.
+func (ic *ImportController) GetImportTemplate(ctx *gin.Context) {
+	profile := ctx.Query("profile")
+	template, err := ic.ImportService.ImportTemplate(models.ImportRequest{Profile: profile})
+	if errors.Is(err, service.ErrImportProfileNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate template"})
+		return
+	}
+
+	name := "invoice-import-template.xlsx"
+	if profile != "" {
+		name = "invoice-import-" + profile + ".xlsx"
+	}
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	ctx.Data(http.StatusOK, xlsxContentType, template)
+}
+
+// respondReport sends an error report workbook as a download, with the counts of the import in headers.
+func respondReport(ctx *gin.Context, status int, name string, report []byte, result *models.ImportResult) {
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if result != nil {
+		ctx.Header("X-Import-Created", strconv.Itoa(result.Created))
+		ctx.Header("X-Import-Updated", strconv.Itoa(result.Updated))
+		ctx.Header("X-Import-Skipped", strconv.Itoa(result.Skipped))
+		ctx.Header("X-Import-Rejected", strconv.Itoa(result.Rejected))
+	}
+	ctx.Data(status, xlsxContentType, report)
+}
+
+// xlsxContentType is the media type of XLSX workbooks.
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// upload is an uploaded file opened for import.
+type upload struct {
+	service.ImportUpload
+	io.Closer
+}
+
+// openUpload opens the file uploaded as field and sniffs its format. It responds with an error and returns false
+// if the file can't be opened, isn't an XLSX workbook or a CSV file, or isn't in the format required.
+func openUpload(ctx *gin.Context, field string, header *multipart.FileHeader, required string) (*upload, bool) {
+	f, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return nil, false
+	}
+	reader := bufio.NewReader(f)
+	head, _ := reader.Peek(512)
+	format := utils.DetectImportFormat(head)
+	switch {
+	case format == "":
+		f.Close()
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": header.Filename + " is neither an XLSX workbook nor a CSV file"})
+		return nil, false
+	case required != "" && format != required:
+		f.Close()
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": header.Filename + " must be a " + strings.ToUpper(required) + " file"})
+		return nil, false
+	}
+	return &upload{ImportUpload: service.ImportUpload{Field: field, Name: header.Filename, Format: format, Reader: reader}, Closer: f}, true
+}
+
+// respondImportError responds to an error that stopped an import and returns false, or returns true if there was none.
+func respondImportError(ctx *gin.Context, err error) bool {
+	var fileErr *service.ImportFileError
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrImportProfileNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &fileErr):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": fileErr.Error(), "errors": fileErr.Errors})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process file"})
+	}
+	return false
 }
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportJobController defines the controller layer for import job operations
+type ImportJobController struct {
+	ImportJobService *service.ImportJobService
+}
+
+// NewImportJobController creates a new ImportJobController instance
+func NewImportJobController(importJobService *service.ImportJobService) *ImportJobController {
+	return &ImportJobController{ImportJobService: importJobService}
+}
+
+// GetImportJob reports the status and progress of an import job, with its errors and result once it has finished
+func (jc *ImportJobController) GetImportJob(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+
+	job, err := jc.ImportJobService.GetImportJob(id)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve import job"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// CancelImportJob cancels a queued import job, or stops a running one
+func (jc *ImportJobController) CancelImportJob(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+
+	job, err := jc.ImportJobService.CancelImportJob(id)
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrImportJobFinished) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel import job"})
+		return
+	}
+
+	message := "Import job cancelled successfully"
+	if job.Status == models.ImportJobRunning {
+		message = "Import job is stopping"
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": message, "job": job})
+}
+
+// GetImportJobReport downloads the error report of a finished import job
+func (jc *ImportJobController) GetImportJobReport(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+
+	report, name, err := jc.ImportJobService.ImportJobReport(id)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+	case errors.Is(err, service.ErrImportJobUnfinished):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrImportReportUnsupported):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNothingToReport):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build the error report"})
+	default:
+		respondReport(ctx, http.StatusOK, name, report, nil)
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportProfileController defines the controller layer for import profile operations
+type ImportProfileController struct {
+	ImportProfileService *service.ImportProfileService
+}
+
+// NewImportProfileController creates a new ImportProfileController instance
+func NewImportProfileController(importProfileService *service.ImportProfileService) *ImportProfileController {
+	return &ImportProfileController{ImportProfileService: importProfileService}
+}
+
+// GetImportProfiles lists all import profiles
+func (pc *ImportProfileController) GetImportProfiles(ctx *gin.Context) {
+	profiles, err := pc.ImportProfileService.GetImportProfiles()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve import profiles"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"import_profiles": profiles})
+}
+
+// GetImportProfile retrieves a single import profile by name
+func (pc *ImportProfileController) GetImportProfile(ctx *gin.Context) {
+	profile, err := pc.ImportProfileService.GetImportProfile(ctx.Param("name"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve import profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"import_profile": profile})
+}
+
+// CreateImportProfile handles the creation of a new import profile
+func (pc *ImportProfileController) CreateImportProfile(ctx *gin.Context) {
+	var payload models.ImportProfileRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	profile := importProfileFromRequest(payload)
+	if err := utils.ValidateImportProfile(profile, service.ImportFields()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pc.ImportProfileService.CreateImportProfile(&profile); err != nil {
+		if err == repository.ErrImportProfileExists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Import profile already exists"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Import profile created successfully", "import_profile": profile})
+}
+
+// UpdateImportProfile replaces the settings of the import profile named in the URL
+func (pc *ImportProfileController) UpdateImportProfile(ctx *gin.Context) {
+	var payload models.ImportProfileRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	payload.Name = ctx.Param("name")
+
+	profile := importProfileFromRequest(payload)
+	if err := utils.ValidateImportProfile(profile, service.ImportFields()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pc.ImportProfileService.UpdateImportProfile(&profile); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update import profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Import profile updated successfully", "import_profile": profile})
+}
+
+// DeleteImportProfile deletes an import profile by name
+func (pc *ImportProfileController) DeleteImportProfile(ctx *gin.Context) {
+	if err := pc.ImportProfileService.DeleteImportProfile(ctx.Param("name")); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete import profile"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Import profile deleted successfully"})
+}
+
+// importProfileFromRequest maps a request body to an import profile, defaulting the decimal separator to ".".
+func importProfileFromRequest(payload models.ImportProfileRequest) models.ImportProfile {
+	profile := models.ImportProfile{
+		Name:             payload.Name,
+		InvoiceSheet:     payload.InvoiceSheet,
+		ProductSheet:     payload.ProductSheet,
+		Columns:          payload.Columns,
+		DateFormat:       payload.DateFormat,
+		DecimalSeparator: payload.DecimalSeparator,
+		Defaults:         payload.Defaults,
+	}
+	if profile.DecimalSeparator == "" {
+		profile.DecimalSeparator = "."
+	}
+	if profile.Columns == nil {
+		profile.Columns = map[string]string{}
+	}
+	if profile.Defaults == nil {
+		profile.Defaults = map[string]string{}
+	}
+	return profile
+}
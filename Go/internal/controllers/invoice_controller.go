@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
 	"widatech-technical-challenge/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -21,19 +23,29 @@ func NewInvoiceController(invoiceService *service.InvoiceService) *InvoiceContro
 
 // CreateInvoice handles the creation of a new invoice
 func (ic *InvoiceController) CreateInvoice(ctx *gin.Context) {
-	var invoice models.Invoice
-	if err := ctx.ShouldBindJSON(&invoice); err != nil {
+	var payload models.CreateInvoiceRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
 	// Use the service layer to create the invoice
-	if err := ic.InvoiceService.CreateInvoice(invoice); err != nil {
+	invoice := payload.ToInvoice()
+	warnings, err := ic.InvoiceService.CreateInvoice(&invoice)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidInvoice) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrInvoiceExists) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "Invoice created successfully", "invoice": invoice})
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Invoice created successfully", "invoice": models.NewInvoiceResponse(invoice), "warnings": warnings})
 }
 
 // GetInvoice retrieves a single invoice by ID and calculates the total cash and total profit
@@ -44,41 +56,66 @@ func (ic *InvoiceController) GetInvoice(ctx *gin.Context) {
 		return
 	}
 
-	// Retrieve invoice, total profit, and total cash from the service
-	invoice, totalProfit, totalCash, err := ic.InvoiceService.GetInvoices(payload)
+	// Retrieve invoice, total profit, total cash and tax summary from the service
+	invoice, totals, err := ic.InvoiceService.GetInvoices(payload)
 	if err == sql.ErrNoRows {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
 	}
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invoice"})
 		return
 	}
 
-	// Return the invoice data along with total profit and total cash
+	// Return the invoice data along with total profit, total cash and tax summary
 	ctx.JSON(http.StatusOK, gin.H{
-		"invoice":     invoice,
-		"totalProfit": totalProfit,
-		"totalCash":   totalCash,
+		"invoice":      models.NewInvoiceResponses(invoice),
+		"totalProfit":  totals.TotalProfit,
+		"totalCash":    totals.TotalCash,
+		"taxSummary":   totals.Tax,
+		"baseCurrency": totals.BaseCurrency,
+		"byCurrency":   totals.ByCurrency,
 	})
 }
 
 // UpdateInvoice updates an existing invoice
 func (ic *InvoiceController) UpdateInvoice(ctx *gin.Context) {
 
-	var invoice models.UpdateInvoiceRequest
-	if err := ctx.ShouldBindJSON(&invoice); err != nil {
+	var payload models.UpdateInvoiceRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	if err := ic.InvoiceService.UpdateInvoice(invoice); err != nil {
+	invoice, warnings, err := ic.InvoiceService.UpdateInvoice(payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrInvoiceNotEditable) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		var validationErr *repository.ValidationError
+		if errors.As(err, &validationErr) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "issues": validationErr.Issues})
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidInvoice) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Invoice updated successfully", "invoice": invoice})
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invoice updated successfully", "invoice": models.NewInvoiceResponse(invoice), "warnings": warnings})
 }
 
 // DeleteInvoice deletes an invoice by invoice_no
@@ -90,9 +127,51 @@ func (ic *InvoiceController) DeleteInvoice(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 			return
 		}
+		if errors.Is(err, repository.ErrInvoiceNotEditable) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invoice"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
 }
+
+// IssueInvoice issues a draft invoice, after which only its notes can be changed
+func (ic *InvoiceController) IssueInvoice(ctx *gin.Context) {
+	if err := ic.InvoiceService.IssueInvoice(ctx.Param("invoiceno")); err != nil {
+		respondTransitionError(ctx, err, "Failed to issue invoice")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invoice issued successfully"})
+}
+
+// VoidInvoice voids a draft or issued invoice, recording the reason
+func (ic *InvoiceController) VoidInvoice(ctx *gin.Context) {
+	var payload models.VoidInvoiceRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at least 5 characters is required"})
+		return
+	}
+
+	if err := ic.InvoiceService.VoidInvoice(ctx.Param("invoiceno"), payload.Reason); err != nil {
+		respondTransitionError(ctx, err, "Failed to void invoice")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invoice voided successfully"})
+}
+
+// respondTransitionError maps the errors of a status change to responses.
+func respondTransitionError(ctx *gin.Context, err error, failed string) {
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	case errors.Is(err, repository.ErrInvalidStatusTransition):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": failed})
+	}
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NumberSequenceController defines the controller layer for number sequence operations
+type NumberSequenceController struct {
+	NumberSequenceService *service.NumberSequenceService
+}
+
+// NewNumberSequenceController creates a new NumberSequenceController instance
+func NewNumberSequenceController(numberSequenceService *service.NumberSequenceService) *NumberSequenceController {
+	return &NumberSequenceController{NumberSequenceService: numberSequenceService}
+}
+
+// GetNumberSequences lists all number sequences
+func (nc *NumberSequenceController) GetNumberSequences(ctx *gin.Context) {
+	sequences, err := nc.NumberSequenceService.GetNumberSequences()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve number sequences"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"number_sequences": sequences})
+}
+
+// GetNumberSequence retrieves a single number sequence by name
+func (nc *NumberSequenceController) GetNumberSequence(ctx *gin.Context) {
+	sequence, err := nc.NumberSequenceService.GetNumberSequence(ctx.Param("name"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Number sequence not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve number sequence"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"number_sequence": sequence})
+}
+
+// UpdateNumberSequence changes the pattern of a number sequence
+func (nc *NumberSequenceController) UpdateNumberSequence(ctx *gin.Context) {
+	var payload models.NumberSequenceRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := utils.ValidateNumberPattern(payload.Pattern); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sequence := models.NumberSequence{Name: ctx.Param("name"), Pattern: payload.Pattern}
+	if err := nc.NumberSequenceService.UpdateNumberSequence(&sequence); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Number sequence not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update number sequence"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Number sequence updated successfully", "number_sequence": sequence})
+}
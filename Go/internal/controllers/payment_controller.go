@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentController defines the controller layer for payments on credit invoices
+type PaymentController struct {
+	PaymentService *service.PaymentService
+}
+
+// NewPaymentController creates a new PaymentController instance
+func NewPaymentController(paymentService *service.PaymentService) *PaymentController {
+	return &PaymentController{PaymentService: paymentService}
+}
+
+// GetPayments lists the payments of an invoice along with its outstanding balance and status
+func (pc *PaymentController) GetPayments(ctx *gin.Context) {
+	payments, balance, err := pc.PaymentService.GetPayments(ctx.Param("invoiceno"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payments"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payments": payments, "balance": balance})
+}
+
+// RecordPayment records a partial or full payment on a credit invoice
+func (pc *PaymentController) RecordPayment(ctx *gin.Context) {
+	var payload models.PaymentRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	payment := models.Payment{
+		InvoiceNo:     ctx.Param("invoiceno"),
+		Amount:        payload.Amount,
+		PaidAt:        payload.PaidAt,
+		PaymentMethod: payload.PaymentMethod,
+		Reference:     payload.Reference,
+		Notes:         payload.Notes,
+	}
+	balance, err := pc.PaymentService.RecordPayment(&payment)
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	case errors.Is(err, repository.ErrInvoiceNotOnCredit), errors.Is(err, repository.ErrInvalidPayment):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Payment recorded successfully", "payment": payment, "balance": balance})
+}
+
+// DeletePayment removes a payment recorded by mistake
+func (pc *PaymentController) DeletePayment(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	if err := pc.PaymentService.DeletePayment(ctx.Param("invoiceno"), id); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Payment deleted successfully"})
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentMethodController defines the controller layer for payment method operations
+type PaymentMethodController struct {
+	PaymentMethodService *service.PaymentMethodService
+}
+
+// NewPaymentMethodController creates a new PaymentMethodController instance
+func NewPaymentMethodController(paymentMethodService *service.PaymentMethodService) *PaymentMethodController {
+	return &PaymentMethodController{PaymentMethodService: paymentMethodService}
+}
+
+// GetPaymentMethods lists all payment methods
+func (pc *PaymentMethodController) GetPaymentMethods(ctx *gin.Context) {
+	paymentMethods, err := pc.PaymentMethodService.GetPaymentMethods()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payment methods"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payment_methods": paymentMethods})
+}
+
+// GetPaymentMethod retrieves a single payment method by code
+func (pc *PaymentMethodController) GetPaymentMethod(ctx *gin.Context) {
+	paymentMethod, err := pc.PaymentMethodService.GetPaymentMethod(ctx.Param("code"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment method not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payment method"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payment_method": paymentMethod})
+}
+
+// CreatePaymentMethod handles the creation of a new payment method
+func (pc *PaymentMethodController) CreatePaymentMethod(ctx *gin.Context) {
+	var payload models.PaymentMethodRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	paymentMethod := paymentMethodFromRequest(payload)
+	if err := utils.ValidatePaymentMethod(paymentMethod); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pc.PaymentMethodService.CreatePaymentMethod(paymentMethod); err != nil {
+		if err == repository.ErrPaymentMethodExists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Payment method already exists"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment method"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Payment method created successfully", "payment_method": paymentMethod})
+}
+
+// UpdatePaymentMethod updates the payment method identified by the code in the URL
+func (pc *PaymentMethodController) UpdatePaymentMethod(ctx *gin.Context) {
+	var payload models.PaymentMethodRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	payload.Code = ctx.Param("code")
+
+	paymentMethod := paymentMethodFromRequest(payload)
+	if err := utils.ValidatePaymentMethod(paymentMethod); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pc.PaymentMethodService.UpdatePaymentMethod(paymentMethod); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment method not found"})
+		case repository.ErrPaymentMethodCreditInUse:
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Payment method is used by existing invoices, so is_credit can't change"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment method"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Payment method updated successfully", "payment_method": paymentMethod})
+}
+
+// DeletePaymentMethod deletes a payment method by code
+func (pc *PaymentMethodController) DeletePaymentMethod(ctx *gin.Context) {
+	if err := pc.PaymentMethodService.DeletePaymentMethod(ctx.Param("code")); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment method not found"})
+		case repository.ErrPaymentMethodInUse:
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Payment method is used by existing invoices; deactivate it instead"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment method"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Payment method deleted successfully"})
+}
+
+// paymentMethodFromRequest maps a request body to a payment method, defaulting it to active.
+func paymentMethodFromRequest(payload models.PaymentMethodRequest) models.PaymentMethod {
+	paymentMethod := models.PaymentMethod{
+		Code:     payload.Code,
+		Name:     payload.Name,
+		Active:   true,
+		IsCredit: payload.IsCredit,
+	}
+	if payload.Active != nil {
+		paymentMethod.Active = *payload.Active
+	}
+	return paymentMethod
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductController defines the controller layer for the products of draft invoices
+type ProductController struct {
+	ProductService *service.ProductService
+}
+
+// NewProductController creates a new ProductController instance
+func NewProductController(productService *service.ProductService) *ProductController {
+	return &ProductController{ProductService: productService}
+}
+
+// AddProduct adds a product to a draft invoice
+func (pc *ProductController) AddProduct(ctx *gin.Context) {
+	var payload models.ProductRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	product := payload.ToProduct()
+	warnings, err := pc.ProductService.AddProduct(ctx.Param("invoiceno"), &product)
+	if err != nil {
+		respondProductError(ctx, err, "Invoice not found", "Failed to add product")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Product added successfully", "product": models.NewProductResponse(product), "warnings": warnings})
+}
+
+// UpdateProduct replaces a product of a draft invoice
+func (pc *ProductController) UpdateProduct(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	var payload models.ProductRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	product := payload.ToProduct()
+	product.ID = id
+	warnings, err := pc.ProductService.UpdateProduct(ctx.Param("invoiceno"), &product)
+	if err != nil {
+		respondProductError(ctx, err, "Invoice or product not found", "Failed to update product")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Product updated successfully", "product": models.NewProductResponse(product), "warnings": warnings})
+}
+
+// DeleteProduct removes a product from a draft invoice
+func (pc *ProductController) DeleteProduct(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := pc.ProductService.DeleteProduct(ctx.Param("invoiceno"), id); err != nil {
+		respondProductError(ctx, err, "Invoice or product not found", "Failed to delete product")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
+
+// respondProductError maps the errors of the product endpoints to responses.
+func respondProductError(ctx *gin.Context, err error, notFound, failed string) {
+	switch {
+	case err == sql.ErrNoRows:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, repository.ErrInvoiceNotEditable):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrInvalidInvoice):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": failed})
+	}
+}
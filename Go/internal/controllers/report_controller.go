@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportController defines the controller layer for reports
+type ReportController struct {
+	ReportService *service.ReportService
+}
+
+// NewReportController creates a new ReportController instance
+func NewReportController(reportService *service.ReportService) *ReportController {
+	return &ReportController{ReportService: reportService}
+}
+
+// GetSalesReport returns revenue, profit and tax for a period in the base currency, with per-currency subtotals
+func (rc *ReportController) GetSalesReport(ctx *gin.Context) {
+	var payload models.ReportRequest
+	if err := ctx.ShouldBindQuery(&payload); err != nil || payload.To.Before(payload.From) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to (YYYY-MM-DD) are required, with from not after to"})
+		return
+	}
+
+	report, err := rc.ReportService.GetSalesReport(payload.From, payload.To)
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sales report"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// GetAgingReport returns the accounts-receivable aging of open credit invoices as of a date (default: today)
+func (rc *ReportController) GetAgingReport(ctx *gin.Context) {
+	var payload models.AgingReportRequest
+	if err := ctx.ShouldBindQuery(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be a date (YYYY-MM-DD)"})
+		return
+	}
+	if payload.AsOf.IsZero() {
+		now := time.Now()
+		payload.AsOf = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	report, err := rc.ReportService.GetAgingReport(payload.AsOf)
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build aging report"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"report": report})
+}
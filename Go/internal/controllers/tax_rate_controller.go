@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaxRateController defines the controller layer for tax rate operations
+type TaxRateController struct {
+	TaxRateService *service.TaxRateService
+}
+
+// NewTaxRateController creates a new TaxRateController instance
+func NewTaxRateController(taxRateService *service.TaxRateService) *TaxRateController {
+	return &TaxRateController{TaxRateService: taxRateService}
+}
+
+// GetTaxRates lists all tax rates
+func (tc *TaxRateController) GetTaxRates(ctx *gin.Context) {
+	taxRates, err := tc.TaxRateService.GetTaxRates()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tax rates"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tax_rates": taxRates})
+}
+
+// GetTaxRate retrieves a single tax rate by code
+func (tc *TaxRateController) GetTaxRate(ctx *gin.Context) {
+	taxRate, err := tc.TaxRateService.GetTaxRate(ctx.Param("code"))
+	if err == sql.ErrNoRows {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tax_rate": taxRate})
+}
+
+// CreateTaxRate handles the creation of a new tax rate
+func (tc *TaxRateController) CreateTaxRate(ctx *gin.Context) {
+	var payload models.TaxRateRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	taxRate := taxRateFromRequest(payload)
+	if err := utils.ValidateTaxRate(taxRate); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tc.TaxRateService.CreateTaxRate(taxRate); err != nil {
+		if err == repository.ErrTaxRateExists {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Tax rate already exists"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Tax rate created successfully", "tax_rate": taxRate})
+}
+
+// UpdateTaxRate updates the tax rate identified by the code in the URL
+func (tc *TaxRateController) UpdateTaxRate(ctx *gin.Context) {
+	var payload models.TaxRateRequest
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	payload.Code = ctx.Param("code")
+
+	taxRate := taxRateFromRequest(payload)
+	if err := utils.ValidateTaxRate(taxRate); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tc.TaxRateService.UpdateTaxRate(taxRate); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tax rate"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tax rate updated successfully", "tax_rate": taxRate})
+}
+
+// DeleteTaxRate deletes a tax rate by code
+func (tc *TaxRateController) DeleteTaxRate(ctx *gin.Context) {
+	if err := tc.TaxRateService.DeleteTaxRate(ctx.Param("code")); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+		case repository.ErrTaxRateInUse:
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Tax rate is used by existing products; deactivate it instead"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tax rate"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted successfully"})
+}
+
+// taxRateFromRequest maps a request body to a tax rate, defaulting it to active.
+func taxRateFromRequest(payload models.TaxRateRequest) models.TaxRate {
+	taxRate := models.TaxRate{
+		Code:   payload.Code,
+		Name:   payload.Name,
+		Rate:   *payload.Rate,
+		Active: true,
+	}
+	if payload.Active != nil {
+		taxRate.Active = *payload.Active
+	}
+	return taxRate
+}
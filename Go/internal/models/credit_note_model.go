@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// CreditNote represents the credit_notes table in the database.
+//
+// A credit note records goods returned against an issued invoice. Its lines
+// reverse part of the original product lines, so it counts as negative
+// revenue and profit on its own date.
+type CreditNote struct {
+	ID           int              `json:"id" db:"id"`                         // Unique ID for the credit note
+	CreditNoteNo string           `json:"credit_note_no" db:"credit_note_no"` // Credit note number, unique
+	InvoiceNo    string           `json:"invoice_no" db:"invoice_no"`         // Original invoice being credited
+	Date         time.Time        `json:"date" db:"date"`                     // Date of the credit note
+	Reason       string           `json:"reason" db:"reason"`                 // Why the goods were returned
+	Currency     string           `json:"currency" db:"-"`                    // Currency of the original invoice
+	TaxMode      string           `json:"tax_mode" db:"-"`                    // Tax mode of the original invoice
+	PaymentType  string           `json:"payment_type" db:"-"`                // Payment method of the original invoice
+	IsCredit     bool             `json:"-" db:"-"`                           // Whether that payment method is a credit method
+	TaxTotal     Money            `json:"tax_total" db:"tax_total"`           // Tax refunded across the lines
+	TotalAmount  Money            `json:"total_amount" db:"total_amount"`     // Amount credited, including exclusive tax
+	Lines        []CreditNoteLine `json:"lines"`                              // Product lines returned
+}
+
+// CreditNoteLine represents the credit_note_lines table in the database.
+type CreditNoteLine struct {
+	ID         int     `json:"id" db:"id"`                   // Unique ID for the line
+	ProductID  int     `json:"product_id" db:"product_id"`   // Product line of the original invoice
+	ItemName   string  `json:"item_name" db:"-"`             // Name of the returned product
+	Quantity   int     `json:"quantity" db:"quantity"`       // Quantity returned
+	TotalCost  Money   `json:"total_cost" db:"total_cost"`   // Cost of the returned goods
+	TotalPrice Money   `json:"total_price" db:"total_price"` // Price credited, after discount
+	TaxCode    string  `json:"tax_code,omitempty" db:"-"`    // Tax code of the original product line
+	TaxRate    Percent `json:"tax_rate" db:"-"`              // Tax rate of the original product line
+	TaxAmount  Money   `json:"tax_amount" db:"tax_amount"`   // Tax refunded on total_price
+}
+
+// Reversal returns the line as a negative product line, so it can be totalled alongside invoices.
+func (l CreditNoteLine) Reversal() Product {
+	return Product{
+		ID:         l.ProductID,
+		ItemName:   l.ItemName,
+		Quantity:   -l.Quantity,
+		TotalCost:  l.TotalCost.Neg(),
+		TotalPrice: l.TotalPrice.Neg(),
+		TaxCode:    l.TaxCode,
+		TaxRate:    l.TaxRate,
+		TaxAmount:  l.TaxAmount.Neg(),
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ExchangeRate represents the exchange_rates table in the database.
+// A rate applies from its date until the next rate for the same currency.
+type ExchangeRate struct {
+	ID           int       `json:"id" db:"id"`                       // Unique ID for the rate
+	BaseCurrency string    `json:"base_currency" db:"base_currency"` // Currency the rate converts into
+	Currency     string    `json:"currency" db:"currency"`           // Currency the rate converts from
+	RateDate     time.Time `json:"rate_date" db:"rate_date"`         // First day the rate applies
+	Rate         Rate      `json:"rate" db:"rate"`                   // Base currency units per unit of currency
+}
+
+// CurrencySubtotal holds the totals of the invoices and credit notes in one currency,
+// both in that currency and converted into the base currency.
+type CurrencySubtotal struct {
+	Currency        string `json:"currency"`
+	InvoiceCount    int    `json:"invoice_count"`
+	CreditNoteCount int    `json:"credit_note_count"`
+	Revenue         Money  `json:"revenue"`      // Sales excluding tax, in Currency
+	Cost            Money  `json:"cost"`         // Cost of goods sold, in Currency
+	Profit          Money  `json:"profit"`       // Revenue minus cost, in Currency
+	Tax             Money  `json:"tax"`          // Tax charged, in Currency
+	BaseRevenue     Money  `json:"base_revenue"` // Revenue converted into the base currency
+	BaseProfit      Money  `json:"base_profit"`  // Profit converted into the base currency
+	BaseTax         Money  `json:"base_tax"`     // Tax converted into the base currency
+}
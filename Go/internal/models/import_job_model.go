@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Statuses of an import job.
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed" // The file was processed; rows may still have been rejected
+	ImportJobFailed    = "failed"    // The file couldn't be processed at all
+	ImportJobCancelled = "cancelled"
+)
+
+// ImportJob represents the import_jobs table in the database: an upload imported in the background.
+type ImportJob struct {
+	ID              int           `json:"id" db:"id"`
+	Status          string        `json:"status" db:"status"` // queued | running | completed | failed | cancelled
+	Format          string        `json:"format" db:"format"` // xlsx | csv
+	DryRun          bool          `json:"dry_run" db:"dry_run"`
+	Atomic          bool          `json:"atomic" db:"atomic"`
+	Profile         string        `json:"profile,omitempty" db:"profile"`
+	OnConflict      string        `json:"on_conflict" db:"on_conflict"` // error | skip | update | replace
+	Processed       int           `json:"processed" db:"processed"`     // Invoice rows processed so far
+	Failed          int           `json:"failed" db:"failed"`           // Invoice rows rejected so far
+	Errors          []ImportError `json:"errors" db:"errors"`           // Errors of the finished job
+	Result          *ImportResult `json:"result,omitempty" db:"result"`
+	Error           string        `json:"error,omitempty" db:"error"` // Why the job failed
+	CancelRequested bool          `json:"cancel_requested" db:"cancel_requested"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	StartedAt       *time.Time    `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt      *time.Time    `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ImportJobFile is a file uploaded for an import job.
+type ImportJobFile struct {
+	Field   string // Form field of the upload: file, invoices or products
+	Name    string
+	Content []byte
+}
@@ -0,0 +1,114 @@
+package models
+
+// Formats of imported files.
+const (
+	ImportFormatXLSX = "xlsx"
+	ImportFormatCSV  = "csv"
+)
+
+// Import modes. A partial import keeps the valid invoices of a file; an atomic import keeps all or none.
+const (
+	ImportModePartial = "partial"
+	ImportModeAtomic  = "atomic"
+)
+
+// Ways an import handles an invoice number that is already taken.
+const (
+	ImportConflictError   = "error"   // Reject the row (default)
+	ImportConflictSkip    = "skip"    // Leave the existing invoice as it is
+	ImportConflictUpdate  = "update"  // Change the fields given, keep the rest, and match product lines by item name
+	ImportConflictReplace = "replace" // Overwrite the invoice and all its product lines
+)
+
+// Actions taken on an invoice row by an import.
+const (
+	ImportActionCreate   = "create"
+	ImportActionUpdate   = "update"
+	ImportActionSkip     = "skip"
+	ImportActionReject   = "reject"
+	ImportActionRollback = "rollback" // Valid, but undone because another row of an atomic import was rejected
+)
+
+// Codes of import errors. Rows that break a validation rule report the rule name as their code, e.g. min_length.
+const (
+	ImportErrorRequired      = "required"             // A cell that links the sheets is empty
+	ImportErrorInvalidNumber = "invalid_number"       // A numeric cell can't be parsed
+	ImportErrorInvalidDate   = "invalid_date"         // A date cell can't be parsed
+	ImportErrorAmbiguousDate = "ambiguous_date"       // A date reads as different days in different formats
+	ImportErrorDuplicate     = "duplicate_invoice"    // The invoice number is already taken
+	ImportErrorNotEditable   = "invoice_not_editable" // The existing invoice can't be updated or replaced
+	ImportErrorFailed        = "import_failed"        // The invoice couldn't be saved
+
+	ImportErrorEmptyRow        = "empty_row"         // A row between rows with values is empty
+	ImportErrorDuplicateInFile = "duplicate_in_file" // The invoice number is on several rows of the invoice sheet
+	ImportErrorNoProducts      = "no_products"       // No product row has the invoice number
+	ImportErrorOrphanProduct   = "orphan_product"    // A product row's invoice number isn't in the invoice sheet
+
+	ImportErrorInvalidCSV      = "invalid_csv"      // A CSV file can't be read
+	ImportErrorInvalidXLSX     = "invalid_xlsx"     // A zip archive isn't a workbook, or is damaged
+	ImportErrorMissingSheet    = "missing_sheet"    // The workbook has no sheet by that name
+	ImportErrorMissingColumn   = "missing_column"   // A required column has no header in the sheet
+	ImportErrorDuplicateColumn = "duplicate_column" // Two headers map to the same field
+)
+
+// ImportError locates a problem in the imported file. Column, header and value are empty
+// when the problem concerns the row as a whole.
+type ImportError struct {
+	Sheet     string `json:"sheet"`
+	Row       int    `json:"row"`              // 1-based row number
+	Column    string `json:"column,omitempty"` // Column letter, e.g. C
+	Header    string `json:"header,omitempty"` // Header of the column in the file
+	Value     string `json:"value,omitempty"`  // Raw cell value
+	Code      string `json:"code"`
+	InvoiceNo string `json:"invoice_no,omitempty"`
+	Message   string `json:"error"`
+}
+
+// ImportResult summarizes an import: what happened to each invoice row of the file.
+// On a dry run nothing is written, and the result describes what a real import would do.
+// An atomic import with a rejected row writes nothing either; its valid rows are reported as rolled back.
+// The rows, errors and warnings are listed up to a limit, past which they are only counted, so the
+// result of a large file stays small; the rejected invoice rows are summarized as ranges instead.
+type ImportResult struct {
+	Format       string              `json:"format"`      // xlsx | csv
+	Mode         string              `json:"mode"`        // partial | atomic
+	OnConflict   string              `json:"on_conflict"` // error | skip | update | replace
+	DryRun       bool                `json:"dry_run"`
+	Cancelled    bool                `json:"cancelled,omitempty"` // The import job was cancelled before the end of the file
+	Processed    int                 `json:"processed"`           // Invoice rows processed, listed in rows or not
+	LastRow      int                 `json:"last_row,omitempty"`  // Last invoice row processed, where a cancelled import stopped
+	Created      int                 `json:"created"`             // Invoices created, or that would be created on a dry run
+	Updated      int                 `json:"updated"`             // Existing invoices updated or replaced
+	Skipped      int                 `json:"skipped"`             // Existing invoices skipped
+	Rejected     int                 `json:"rejected"`            // Rows with errors, including product rows no invoice row takes
+	RolledBack   int                 `json:"rolled_back"`         // Valid rows undone by an atomic import that had errors
+	RejectedRows string              `json:"rejected_rows"`       // Rejected invoice rows as ranges, e.g. 3,7-9
+	Truncated    bool                `json:"truncated,omitempty"` // Rows, errors or warnings past the limit were left out
+	Rows         []ImportRow         `json:"rows"`
+	Errors       []ImportError       `json:"errors"`
+	Warnings     []map[string]string `json:"warnings"`
+}
+
+// ImportRow is the outcome of one row of the invoice sheet.
+type ImportRow struct {
+	Row       int              `json:"row"` // 1-based row number in the invoice sheet
+	InvoiceNo string           `json:"invoice_no"`
+	Action    string           `json:"action"`            // create | update | skip | reject | rollback
+	Invoice   *InvoiceResponse `json:"invoice,omitempty"` // On a dry run, the invoice as it would be saved, with its computed totals
+	Errors    []ImportError    `json:"errors,omitempty"`  // Why the row was rejected, in this row or its product rows
+	Warnings  []Issue          `json:"warnings,omitempty"`
+}
+
+// Sheets a row of an imported file is staged as. The rows of a single CSV file are staged as both.
+const (
+	ImportStagedInvoices = "invoices"
+	ImportStagedProducts = "products"
+)
+
+// ImportStagedRow is a row of an imported file staged in the database while the file is imported.
+type ImportStagedRow struct {
+	Row       int      // 1-based row number in the file
+	InvoiceNo string   // Invoice number of the row, "" for none
+	Cells     []string // Values as displayed
+	Raw       []string // Values as stored, nil for CSV files
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ImportProfile represents the import_profiles table in the database: how a partner's spreadsheet
+// layout maps onto the import, applied with ?profile= on the import endpoint.
+type ImportProfile struct {
+	Name             string            `json:"name" db:"name"`                           // Profile name, e.g. distributor-a
+	InvoiceSheet     string            `json:"invoice_sheet" db:"invoice_sheet"`         // Name of the invoice sheet, if not one of the default names
+	ProductSheet     string            `json:"product_sheet" db:"product_sheet"`         // Name of the product sheet, if not one of the default names
+	Columns          map[string]string `json:"columns" db:"columns"`                     // Header of each field in the file, e.g. customer_name: Buyer
+	DateFormat       string            `json:"date_format" db:"date_format"`             // Date format such as DD/MM/YYYY, default DD-MM-YY
+	DecimalSeparator string            `json:"decimal_separator" db:"decimal_separator"` // "." or ","
+	Defaults         map[string]string `json:"defaults" db:"defaults"`                   // Value of each field when its cell is empty or missing
+	UpdatedAt        time.Time         `json:"updated_at" db:"updated_at"`               // When the profile was last changed
+}
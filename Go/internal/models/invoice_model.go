@@ -2,14 +2,60 @@ package models
 
 import "time"
 
+// Invoice statuses. Drafts can be edited freely, issued invoices only have their notes changed,
+// and void invoices are final.
+const (
+	InvoiceStatusDraft  = "draft"
+	InvoiceStatusIssued = "issued"
+	InvoiceStatusVoid   = "void"
+)
+
 // Invoice represents the invoice table in the database.
+//
+// It is the persistence type only: requests bind to CreateInvoiceRequest and
+// responses are written as InvoiceResponse.
 type Invoice struct {
-	ID              int       `json:"id" db:"id" binding:"required"`                             // Unique ID for the invoice
-	InvoiceNo       string    `json:"invoice_no" db:"invoice_no" binding:"required"`             // Invoice number, required field
-	Date            time.Time `json:"date" db:"date" binding:"required"`                         // Date of the invoice creation
-	CustomerName    string    `json:"customer_name" db:"customer_name" binding:"required"`       // Name of the customer, required field
-	SalespersonName string    `json:"salesperson_name" db:"salesperson_name" binding:"required"` // Name of the salesperson, required field
-	PaymentType     string    `json:"payment_type" db:"payment_type" binding:"required"`         // Payment type (Enum: CASH | CREDIT)
-	Notes           string    `json:"notes,omitempty" db:"notes"`                                // Optional field for additional notes
-	Products        []Product `json:"products,omitempty" binding:"required"`       // List of products sold, stored in the product table
+	ID              int        `db:"id"`                 // Unique ID for the invoice, assigned by the database
+	InvoiceNo       string     `db:"invoice_no"`         // Invoice number, generated from the invoice sequence when omitted
+	Date            time.Time  `db:"date"`               // Date of the invoice creation
+	CustomerName    string     `db:"customer_name"`      // Name of the customer
+	SalespersonName string     `db:"salesperson_name"`   // Name of the salesperson
+	PaymentType     string     `db:"payment_type"`       // Payment method code from payment_methods, e.g. CASH
+	Notes           string     `db:"notes"`              // Optional field for additional notes
+	Currency        string     `db:"currency"`           // ISO 4217 currency of all amounts (default: base currency)
+	TaxMode         string     `db:"tax_mode"`           // Whether product prices exclude or include tax (default: exclusive)
+	TaxTotal        Money      `db:"tax_total"`          // Sum of the products' tax, computed on insert
+	TotalAmount     Money      `db:"total_amount"`       // Amount the customer owes including exclusive tax, computed on insert
+	DueDate         *time.Time `db:"due_date"`           // Date a credit invoice must be paid by
+	PaymentTerms    *int       `db:"payment_terms_days"` // Days until due_date, used when due_date is omitted
+	PaidAmount      Money      `db:"-"`                  // Sum of recorded payments
+	CreditedAmount  Money      `db:"-"`                  // Sum of the credit notes raised against the invoice
+	PaymentStatus   string     `db:"-"`                  // unpaid | partial | paid | overdue
+	IsCredit        bool       `db:"-"`                  // Whether the payment method is a credit method, from payment_methods
+	Status          string     `db:"status"`             // draft | issued | void (default on create: draft)
+	IssuedAt        *time.Time `db:"issued_at"`          // When the invoice was issued
+	VoidedAt        *time.Time `db:"voided_at"`          // When the invoice was voided
+	VoidReason      string     `db:"void_reason"`        // Why the invoice was voided
+	Products        []Product  `db:"-"`                  // List of products sold, stored in the product table
+}
+
+// InvoiceTotals holds the aggregates of a set of invoices. Amounts are converted
+// into the base currency at the exchange rate on each invoice date.
+type InvoiceTotals struct {
+	BaseCurrency    string             `json:"base_currency"`
+	InvoiceCount    int                `json:"invoice_count"`
+	CreditNoteCount int                `json:"credit_note_count"`
+	Revenue         Money              `json:"revenue"`      // Sales excluding tax
+	Cost            Money              `json:"cost"`         // Cost of goods sold
+	TotalProfit     Money              `json:"total_profit"` // Revenue minus cost
+	TotalCash       Money              `json:"total_cash"`   // Amount received on invoices with a non-credit payment method, including tax
+	Tax             TaxSummary         `json:"tax"`          // Tax charged across the invoices
+	ByCurrency      []CurrencySubtotal `json:"by_currency"`  // Subtotals per invoice currency
+}
+
+// SalesReport summarizes the invoices dated within a period.
+type SalesReport struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	InvoiceTotals
 }
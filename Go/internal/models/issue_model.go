@@ -0,0 +1,15 @@
+package models
+
+// Severities of a validation issue. Errors reject the invoice; warnings are reported alongside it.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a validation rule that an invoice or one of its products broke.
+type Issue struct {
+	Field    string `json:"field"`    // Field the rule checks, e.g. customer_name or products[1].total_price
+	Rule     string `json:"rule"`     // Name of the rule, e.g. min_length or below_cost
+	Severity string `json:"severity"` // error | warning
+	Message  string `json:"message"`  // Human-readable explanation
+}
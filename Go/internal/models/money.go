@@ -0,0 +1,228 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount stored as a whole number of hundredths, matching the
+// NUMERIC(x, 2) columns. Arithmetic is exact; anything finer than a cent is
+// rounded half to even. It reads and writes JSON numbers and NUMERIC values.
+type Money int64
+
+// Percent is a percentage with two decimals stored as hundredths of a percent,
+// matching the NUMERIC(5, 2) rate columns (11.00% is Percent(1100)).
+type Percent int64
+
+// Rate is an exchange rate stored as millionths, matching NUMERIC(18, 6):
+// the number of base currency units one unit of another currency is worth.
+type Rate int64
+
+// RateOne is the rate of the base currency against itself.
+const RateOne Rate = 1000000
+
+// ParseMoney parses a decimal string such as "1250000", "-12.5" or "1.2E+3".
+func ParseMoney(s string) (Money, error) {
+	v, err := parseDecimal(s, 2)
+	return Money(v), err
+}
+
+// ParsePercent parses a decimal percentage string such as "11" or "12.5".
+func ParsePercent(s string) (Percent, error) {
+	v, err := parseDecimal(s, 2)
+	return Percent(v), err
+}
+
+// ParseRate parses a decimal exchange rate string such as "15850.25".
+func ParseRate(s string) (Rate, error) {
+	v, err := parseDecimal(s, 6)
+	return Rate(v), err
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) Money { return m + o }
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money { return m - o }
+
+// Neg returns -m.
+func (m Money) Neg() Money { return -m }
+
+// Mul returns m multiplied by a whole quantity.
+func (m Money) Mul(n int64) Money { return m * Money(n) }
+
+// MulRatio returns m × num / den rounded half to even.
+func (m Money) MulRatio(num, den int64) Money {
+	product := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num))
+	return Money(divRoundHalfEven(product, big.NewInt(den)).Int64())
+}
+
+// Div returns m / n rounded half to even, e.g. a line total spread over its quantity.
+func (m Money) Div(n int64) Money { return m.MulRatio(1, n) }
+
+// Percent returns p percent of m rounded half to even.
+func (m Money) Percent(p Percent) Money { return m.MulRatio(int64(p), 10000) }
+
+// Convert returns m converted at the given exchange rate, rounded half to even.
+func (m Money) Convert(r Rate) Money { return m.MulRatio(int64(r), int64(RateOne)) }
+
+// String formats the amount with exactly two decimals.
+func (m Money) String() string { return formatDecimal(int64(m), 2) }
+
+// Float64 returns the amount as a float, for display only.
+func (m Money) Float64() float64 { return float64(m) / 100 }
+
+// MarshalJSON writes the amount as a JSON number.
+func (m Money) MarshalJSON() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalJSON accepts a JSON number or a numeric string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalDecimal(data, 2)
+	*m = Money(v)
+	return err
+}
+
+// Scan implements sql.Scanner for NUMERIC columns. NULL scans as zero.
+func (m *Money) Scan(src interface{}) error {
+	v, err := scanDecimal(src, 2)
+	*m = Money(v)
+	return err
+}
+
+// Value implements driver.Valuer, passing the amount as an exact decimal string.
+func (m Money) Value() (driver.Value, error) { return m.String(), nil }
+
+// String formats the percentage with exactly two decimals.
+func (p Percent) String() string { return formatDecimal(int64(p), 2) }
+
+// MarshalJSON writes the percentage as a JSON number.
+func (p Percent) MarshalJSON() ([]byte, error) { return []byte(p.String()), nil }
+
+// UnmarshalJSON accepts a JSON number or a numeric string.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalDecimal(data, 2)
+	*p = Percent(v)
+	return err
+}
+
+// Scan implements sql.Scanner for NUMERIC columns. NULL scans as zero.
+func (p *Percent) Scan(src interface{}) error {
+	v, err := scanDecimal(src, 2)
+	*p = Percent(v)
+	return err
+}
+
+// Value implements driver.Valuer, passing the percentage as an exact decimal string.
+func (p Percent) Value() (driver.Value, error) { return p.String(), nil }
+
+// String formats the rate with exactly six decimals.
+func (r Rate) String() string { return formatDecimal(int64(r), 6) }
+
+// MarshalJSON writes the rate as a JSON number.
+func (r Rate) MarshalJSON() ([]byte, error) { return []byte(r.String()), nil }
+
+// UnmarshalJSON accepts a JSON number or a numeric string.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalDecimal(data, 6)
+	*r = Rate(v)
+	return err
+}
+
+// Scan implements sql.Scanner for NUMERIC columns. NULL scans as zero.
+func (r *Rate) Scan(src interface{}) error {
+	v, err := scanDecimal(src, 6)
+	*r = Rate(v)
+	return err
+}
+
+// Value implements driver.Valuer, passing the rate as an exact decimal string.
+func (r Rate) Value() (driver.Value, error) { return r.String(), nil }
+
+// parseDecimal parses a decimal string into an integer count of 10^-places units,
+// rounding half to even.
+func parseDecimal(s string, places int) (int64, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/") {
+		return 0, fmt.Errorf("invalid decimal value %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(places)))
+	v := divRoundHalfEven(r.Num(), r.Denom())
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("decimal value %q is out of range", s)
+	}
+	return v.Int64(), nil
+}
+
+// formatDecimal formats an integer count of 10^-places units as a decimal string.
+func formatDecimal(v int64, places int) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-v)
+	}
+	scale := pow10(places).Uint64()
+	return fmt.Sprintf("%s%d.%0*d", sign, u/scale, places, u%scale)
+}
+
+// unmarshalDecimal decodes a JSON number or numeric string.
+func unmarshalDecimal(data []byte, places int) (int64, error) {
+	if string(data) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	return parseDecimal(s, places)
+}
+
+// scanDecimal converts a database value.
+func scanDecimal(src interface{}, places int) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return parseDecimal(string(v), places)
+	case string:
+		return parseDecimal(v, places)
+	case int64:
+		return parseDecimal(strconv.FormatInt(v, 10), places)
+	case float64:
+		return parseDecimal(strconv.FormatFloat(v, 'f', -1, 64), places)
+	}
+	return 0, fmt.Errorf("cannot scan %T into a decimal", src)
+}
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divRoundHalfEven divides num by den, rounding ties to the even neighbour.
+func divRoundHalfEven(num, den *big.Int) *big.Int {
+	if den.Sign() < 0 {
+		num = new(big.Int).Neg(num)
+		den = new(big.Int).Neg(den)
+	}
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// Compare twice the remainder with the divisor to decide the rounding direction.
+	cmp := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den)
+	if cmp > 0 || (cmp == 0 && quo.Bit(0) == 1) {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestDivRoundHalfEven(t *testing.T) {
+	tests := []struct {
+		num, den, want int64
+	}{
+		{10, 2, 5},
+		{5, 2, 2},   // 2.5 rounds down to the even 2
+		{7, 2, 4},   // 3.5 rounds up to the even 4
+		{-5, 2, -2}, // ties round to even on both sides of zero
+		{-7, 2, -4},
+		{5, -2, -2}, // a negative divisor moves its sign to the dividend
+		{-7, -2, 4},
+		{1, 3, 0},
+		{2, 3, 1},
+		{-2, 3, -1},
+		{15, 10, 2},
+		{25, 10, 2},
+		{26, 10, 3},
+		{0, 7, 0},
+	}
+	for _, tt := range tests {
+		got := divRoundHalfEven(big.NewInt(tt.num), big.NewInt(tt.den)).Int64()
+		if got != tt.want {
+			t.Errorf("divRoundHalfEven(%d, %d) = %d, want %d", tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"1250000", 125000000},
+		{"-12.5", -1250},
+		{"1.2E+3", 120000},
+		{" 7.10 ", 710},
+		{"0.005", 0}, // half a cent rounds to the even 0
+		{"0.015", 2},
+		{"0.025", 2},
+		{"-0.015", -2},
+		{"0.0051", 1},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1/2", "1,5", "99999999999999999999"} {
+		if got, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Percent
+	}{
+		{"11", 1100},
+		{"12.5", 1250},
+		{"0.125", 12},
+		{"0.135", 14},
+		{"100", 10000},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePercent(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParsePercent("eleven"); err == nil {
+		t.Error("ParsePercent(\"eleven\") succeeded, want an error")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"15850.25", 15850250000},
+		{"1", RateOne},
+		{"0.0000005", 0},
+		{"0.0000015", 2},
+		{"0.0000025", 2},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseRate("1e"); err == nil {
+		t.Error("ParseRate(\"1e\") succeeded, want an error")
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want Money
+	}{
+		{"Div", Money(1000).Div(3), 333},
+		{"Div tie", Money(5).Div(2), 2},
+		{"MulRatio", Money(1000).MulRatio(2, 3), 667},
+		{"Percent", Money(12345).Percent(1100), 1358}, // 11% of 123.45 is 13.5795
+		{"Percent tie", Money(50).Percent(5000), 25},
+		{"Percent half cent", Money(25).Percent(1000), 2}, // 2.5 cents
+		{"Convert", Money(100).Convert(15850250000), 1585025},
+		{"Convert rounding", Money(1).Convert(Rate(1500000)), 2}, // 1.5 cents
+		{"Mul", Money(199).Mul(3), 597},
+		{"Neg", Money(199).Neg(), -199},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDecimalStrings(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Money(125050).String(), "1250.50"},
+		{Money(-5).String(), "-0.05"},
+		{Money(0).String(), "0.00"},
+		{Percent(1100).String(), "11.00"},
+		{Rate(15850250000).String(), "15850.250000"},
+		{Rate(-1).String(), "-0.000001"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type amounts struct {
+		Money   Money   `json:"money"`
+		Percent Percent `json:"percent"`
+		Rate    Rate    `json:"rate"`
+	}
+	in := amounts{Money: -125050, Percent: 1250, Rate: 15850250000}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"money":-1250.50,"percent":12.50,"rate":15850.250000}`; string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+	var out amounts
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		t.Errorf("json.Unmarshal(%s) = %+v, %v, want %+v", data, out, err, in)
+	}
+
+	// Numeric strings and null are accepted too
+	var fromStrings amounts
+	err = json.Unmarshal([]byte(`{"money":"1250.5","percent":null,"rate":"1"}`), &fromStrings)
+	if want := (amounts{Money: 125050, Rate: RateOne}); err != nil || fromStrings != want {
+		t.Errorf("json.Unmarshal of strings = %+v, %v, want %+v", fromStrings, err, want)
+	}
+	if err := json.Unmarshal([]byte(`{"money":"ten"}`), &fromStrings); err == nil {
+		t.Error("json.Unmarshal of a non-numeric string succeeded, want an error")
+	}
+}
+
+func TestDecimalScanValue(t *testing.T) {
+	tests := []struct {
+		src  any
+		want Money
+	}{
+		{[]byte("1250.50"), 125050},
+		{"12.345", 1234},
+		{int64(12), 1200},
+		{float64(1.5), 150},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil || m != tt.want {
+			t.Errorf("Scan(%#v) = %d, %v, want %d", tt.src, m, err, tt.want)
+		}
+		value, err := m.Value()
+		var back Money
+		if err == nil {
+			err = back.Scan(value)
+		}
+		if err != nil || back != m {
+			t.Errorf("Value/Scan round trip of %d = %d, %v", m, back, err)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(true) succeeded, want an error")
+	}
+
+	var p Percent
+	if err := p.Scan([]byte("11.00")); err != nil || p != 1100 {
+		t.Errorf("Percent.Scan = %d, %v, want 1100", p, err)
+	}
+	if value, _ := p.Value(); value != "11.00" {
+		t.Errorf("Percent.Value = %v, want 11.00", value)
+	}
+	var r Rate
+	if err := r.Scan("15850.250000"); err != nil || r != 15850250000 {
+		t.Errorf("Rate.Scan = %d, %v, want 15850250000", r, err)
+	}
+	if value, _ := r.Value(); value != "15850.250000" {
+		t.Errorf("Rate.Value = %v, want 15850.250000", value)
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Names of the number sequences.
+const (
+	SequenceInvoice = "invoice"
+)
+
+// NumberSequence represents the number_sequences table in the database.
+//
+// The pattern mixes literal text with the tokens {YYYY}, {YY}, {MM}, {DD} and
+// {SEQ} or {SEQ:n}, where n zero-pads the counter. The counter restarts
+// whenever the date tokens used by the pattern change.
+type NumberSequence struct {
+	Name      string    `json:"name" db:"name"`             // What the sequence numbers, e.g. invoice
+	Pattern   string    `json:"pattern" db:"pattern"`       // Pattern such as INV-{YYYY}{MM}-{SEQ:5}
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"` // When the pattern was last changed
+}
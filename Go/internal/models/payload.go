@@ -8,11 +8,121 @@ type InvoiceRequest struct {
 	Date time.Time `json:"date" binding:"required"`
 }
 
+type ReportRequest struct {
+	From time.Time `form:"from" time_format:"2006-01-02" binding:"required"` // First invoice date included
+	To   time.Time `form:"to" time_format:"2006-01-02" binding:"required"`   // Last invoice date included
+}
+
 type UpdateInvoiceRequest struct {
 	InvoiceNo       string    `json:"invoice_no" db:"invoice_no" `             // Invoice number, required field
 	Date            time.Time `json:"date" db:"date" `                         // Date of the invoice creation
 	CustomerName    string    `json:"customer_name" db:"customer_name" `       // Name of the customer, required field
 	SalespersonName string    `json:"salesperson_name" db:"salesperson_name" ` // Name of the salesperson, required field
-	PaymentType     string    `json:"payment_type" db:"payment_type" `         // Payment type (Enum: CASH | CREDIT)
+	PaymentType     string    `json:"payment_type" db:"payment_type" `         // Payment method code from payment_methods
 	Notes           string    `json:"notes,omitempty" db:"notes"`              // Optional field for additional notes
+	DueDate         time.Time `json:"due_date" db:"due_date"`                  // Date a credit invoice must be paid by
+}
+
+type TaxRateRequest struct {
+	Code   string   `json:"code"`                          // Tax code, required on create (taken from the URL on update)
+	Name   string   `json:"name" binding:"required,min=2"` // Display name, minLength: 2
+	Rate   *Percent `json:"rate" binding:"required"`       // Rate in percent, 0-100
+	Active *bool    `json:"active"`                        // Defaults to true
+}
+
+type ExchangeRateRequest struct {
+	Currency string    `json:"currency" binding:"required"`  // Currency converted from, e.g. USD
+	RateDate time.Time `json:"rate_date" binding:"required"` // First day the rate applies
+	Rate     Rate      `json:"rate" binding:"required"`      // Base currency units per unit of currency
+}
+
+type ExchangeRateLookupRequest struct {
+	Currency string    `form:"currency" binding:"required"`                      // Currency converted from
+	Date     time.Time `form:"date" time_format:"2006-01-02" binding:"required"` // Day the rate must apply on
+}
+
+type PaymentMethodRequest struct {
+	Code     string `json:"code"`                          // Upper-case code, required on create (taken from the URL on update)
+	Name     string `json:"name" binding:"required,min=2"` // Display name, minLength: 2
+	Active   *bool  `json:"active"`                        // Defaults to true
+	IsCredit bool   `json:"is_credit"`                     // Invoices stay open until payments settle them
+}
+
+type PaymentRequest struct {
+	Amount        Money     `json:"amount" binding:"required"`  // Amount received, must not exceed the outstanding balance
+	PaidAt        time.Time `json:"paid_at" binding:"required"` // Date the payment was received
+	PaymentMethod string    `json:"payment_method"`             // How it was paid, e.g. TRANSFER
+	Reference     string    `json:"reference"`                  // Bank or receipt reference
+	Notes         string    `json:"notes"`                      // Optional field for additional notes
+}
+
+type AgingReportRequest struct {
+	AsOf time.Time `form:"as_of" time_format:"2006-01-02"` // Day the report is taken on (default: today)
+}
+
+type VoidInvoiceRequest struct {
+	Reason string `json:"reason" binding:"required,min=5"` // Why the invoice is voided, minLength: 5
+}
+
+type CreateInvoiceRequest struct {
+	InvoiceNo       string           `json:"invoice_no"`                                             // Invoice number, generated from the invoice sequence when omitted
+	Date            time.Time        `json:"date" binding:"required"`                                // Date of the invoice
+	CustomerName    string           `json:"customer_name" binding:"required,min=2"`                 // Name of the customer, minLength: 2
+	SalespersonName string           `json:"salesperson_name" binding:"required,min=2"`              // Name of the salesperson, minLength: 2
+	PaymentType     string           `json:"payment_type" binding:"required"`                        // Payment method code from payment_methods, e.g. CASH
+	Notes           string           `json:"notes" binding:"omitempty,min=5"`                        // Optional notes, minLength: 5
+	Currency        string           `json:"currency" binding:"omitempty,len=3"`                     // ISO 4217 currency (default: base currency)
+	TaxMode         string           `json:"tax_mode" binding:"omitempty,oneof=exclusive inclusive"` // Whether product prices exclude or include tax (default: exclusive)
+	DueDate         *time.Time       `json:"due_date"`                                               // Date a credit invoice must be paid by
+	PaymentTerms    *int             `json:"payment_terms_days" binding:"omitempty,min=0"`           // Days until due_date, used when due_date is omitted
+	Status          string           `json:"status" binding:"omitempty,oneof=draft issued"`          // draft (default) or issued
+	Products        []ProductRequest `json:"products" binding:"required,min=1,dive"`                 // Products sold, at least one
+}
+
+type ProductRequest struct {
+	ItemName        string  `json:"item_name" binding:"required,min=5"` // Name of the product, minLength: 5
+	Quantity        int     `json:"quantity" binding:"required,min=1"`  // Product quantity, minValue: 1
+	UnitCost        Money   `json:"unit_cost" binding:"min=0"`          // Cost per unit (or send total_cost)
+	UnitPrice       Money   `json:"unit_price" binding:"min=0"`         // Price per unit before discount (or send total_price)
+	DiscountAmount  Money   `json:"discount_amount" binding:"min=0"`    // Discount taken off the line
+	DiscountPercent Percent `json:"discount_percent" binding:"min=0"`   // Discount as a percentage of quantity × unit_price
+	TotalCost       Money   `json:"total_cost" binding:"min=0"`         // Cost of the product sold
+	TotalPrice      Money   `json:"total_price" binding:"min=0"`        // Price of the product sold after discount
+	TaxCode         string  `json:"tax_code"`                           // Tax code from tax_rates, optional
+}
+
+type CreditNoteRequest struct {
+	CreditNoteNo string                  `json:"credit_note_no" binding:"required"`   // Credit note number, unique
+	Date         time.Time               `json:"date" binding:"required"`             // Date of the credit note, on or after the invoice date
+	Reason       string                  `json:"reason" binding:"required,min=5"`     // Why the goods were returned, minLength: 5
+	Lines        []CreditNoteLineRequest `json:"lines" binding:"required,min=1,dive"` // Product lines returned, at least one
+}
+
+type CreditNoteLineRequest struct {
+	ProductID int `json:"product_id" binding:"required"`     // ID of the product line on the original invoice
+	Quantity  int `json:"quantity" binding:"required,min=1"` // Quantity returned, up to what is left of the line
+}
+
+type NumberSequenceRequest struct {
+	Pattern string `json:"pattern" binding:"required"` // Pattern with exactly one {SEQ} or {SEQ:n} token
+}
+
+type ImportRequest struct {
+	DryRun  bool   `form:"dry_run"` // Validate the file and report what would happen without writing anything
+	Atomic  bool   `form:"atomic"`  // Import the whole file in one transaction, or nothing if any row is rejected
+	Profile string `form:"profile"` // Name of the import profile describing the layout of the file
+	Async   bool   `form:"async"`   // Queue the file as an import job and respond right away
+	Report  bool   `form:"report"`  // Respond with the error report workbook when rows weren't imported
+	// What to do with invoice numbers that already exist: error (default), skip, update or replace
+	OnConflict string `form:"on_conflict" binding:"omitempty,oneof=error skip update replace"`
+}
+
+type ImportProfileRequest struct {
+	Name             string            `json:"name"`              // Profile name, taken from the URL on update
+	InvoiceSheet     string            `json:"invoice_sheet"`     // Name of the invoice sheet, optional
+	ProductSheet     string            `json:"product_sheet"`     // Name of the product sheet, optional
+	Columns          map[string]string `json:"columns"`           // Header of each field in the file, optional
+	DateFormat       string            `json:"date_format"`       // Date format such as DD/MM/YYYY, optional
+	DecimalSeparator string            `json:"decimal_separator"` // "." (default) or ","
+	Defaults         map[string]string `json:"defaults"`          // Value of each field when its cell is empty, optional
 }
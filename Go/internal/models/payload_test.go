@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// TestRequestBindingLengths checks the binding tags of the request fields the database holds to a
+// minimum length. Lengths are counted in characters, as PostgreSQL's LENGTH does, so "é" is one
+// character although it takes two bytes.
+func TestRequestBindingLengths(t *testing.T) {
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	creditNote := func(reason string, quantity int) CreditNoteRequest {
+		return CreditNoteRequest{CreditNoteNo: "CN-001", Date: date, Reason: reason, Lines: []CreditNoteLineRequest{{ProductID: 1, Quantity: quantity}}}
+	}
+	rate := Percent(1100)
+	tests := []struct {
+		name    string
+		request any
+		valid   bool
+	}{
+		{"void reason of 4 characters", VoidInvoiceRequest{Reason: "Typé"}, false},
+		{"void reason of 5 characters", VoidInvoiceRequest{Reason: "Typés"}, true},
+		{"credit note reason of 4 characters", creditNote("Casé", 1), false},
+		{"credit note reason of 5 characters", creditNote("Brisé", 1), true},
+		{"credit note line without a quantity", creditNote("Brisé", 0), false},
+		{"tax rate name of 1 character", TaxRateRequest{Name: "É", Rate: &rate}, false},
+		{"tax rate name of 2 characters", TaxRateRequest{Name: "Év", Rate: &rate}, true},
+	}
+	for _, tt := range tests {
+		err := binding.Validator.ValidateStruct(tt.request)
+		if tt.valid && err != nil {
+			t.Errorf("%s: got %v, want no error", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: got no error, want one", tt.name)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+// PaymentMethod represents the payment_methods table in the database.
+type PaymentMethod struct {
+	Code     string `json:"code" db:"code"`           // Code stored in invoices.payment_type, e.g. CASH
+	Name     string `json:"name" db:"name"`           // Display name
+	Active   bool   `json:"active" db:"active"`       // Inactive methods cannot be used on new invoices
+	IsCredit bool   `json:"is_credit" db:"is_credit"` // Invoices stay open until payments settle them
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Payment statuses of an invoice, computed from its payments and due date.
+const (
+	PaymentStatusUnpaid  = "unpaid"
+	PaymentStatusPartial = "partial"
+	PaymentStatusPaid    = "paid"
+	PaymentStatusOverdue = "overdue"
+)
+
+// Payment represents the payments table in the database.
+type Payment struct {
+	ID            int       `json:"id" db:"id"`                                   // Unique ID for the payment
+	InvoiceNo     string    `json:"invoice_no" db:"invoice_no"`                   // Invoice being paid
+	Amount        Money     `json:"amount" db:"amount"`                           // Amount received
+	PaidAt        time.Time `json:"paid_at" db:"paid_at"`                         // Date the payment was received
+	PaymentMethod string    `json:"payment_method,omitempty" db:"payment_method"` // How it was paid, e.g. TRANSFER
+	Reference     string    `json:"reference,omitempty" db:"reference"`           // Bank or receipt reference
+	Notes         string    `json:"notes,omitempty" db:"notes"`                   // Optional field for additional notes
+}
+
+// InvoiceBalance is the payment position of an invoice.
+type InvoiceBalance struct {
+	InvoiceNo         string     `json:"invoice_no"`
+	Status            string     `json:"status"`
+	TotalAmount       Money      `json:"total_amount"`
+	CreditedAmount    Money      `json:"credited_amount"`
+	PaidAmount        Money      `json:"paid_amount"`
+	OutstandingAmount Money      `json:"outstanding_amount"`
+	DueDate           *time.Time `json:"due_date,omitempty"`
+	PaymentStatus     string     `json:"payment_status"`
+}
+
+// AgingBuckets splits outstanding amounts by how many days past due they are.
+type AgingBuckets struct {
+	Current    Money `json:"current"`      // Not yet due
+	Days0To30  Money `json:"days_0_30"`    // 0-30 days past due
+	Days31To60 Money `json:"days_31_60"`   // 31-60 days past due
+	Days61To90 Money `json:"days_61_90"`   // 61-90 days past due
+	Over90     Money `json:"days_over_90"` // More than 90 days past due
+	Total      Money `json:"total"`
+}
+
+// Add puts an outstanding amount into the bucket for daysPastDue.
+func (b *AgingBuckets) Add(amount Money, daysPastDue int) {
+	switch {
+	case daysPastDue < 0:
+		b.Current += amount
+	case daysPastDue <= 30:
+		b.Days0To30 += amount
+	case daysPastDue <= 60:
+		b.Days31To60 += amount
+	case daysPastDue <= 90:
+		b.Days61To90 += amount
+	default:
+		b.Over90 += amount
+	}
+	b.Total += amount
+}
+
+// AgingInvoice is an open credit invoice in an aging report.
+type AgingInvoice struct {
+	InvoiceNo         string    `json:"invoice_no"`
+	CustomerName      string    `json:"customer_name"`
+	Date              time.Time `json:"date"`
+	DueDate           time.Time `json:"due_date"`
+	Currency          string    `json:"currency"`
+	OutstandingAmount Money     `json:"outstanding_amount"`      // In the invoice currency
+	BaseOutstanding   Money     `json:"base_outstanding_amount"` // Converted into the base currency
+	DaysPastDue       int       `json:"days_past_due"`           // Negative while not yet due
+}
+
+// CustomerAging holds a customer's outstanding amounts per bucket.
+type CustomerAging struct {
+	CustomerName string `json:"customer_name"`
+	AgingBuckets
+}
+
+// AgingReport is the accounts-receivable aging of open credit invoices on a date,
+// in the base currency.
+type AgingReport struct {
+	AsOf         time.Time       `json:"as_of"`
+	BaseCurrency string          `json:"base_currency"`
+	Totals       AgingBuckets    `json:"totals"`
+	Customers    []CustomerAging `json:"customers"`
+	Invoices     []AgingInvoice  `json:"invoices"`
+}
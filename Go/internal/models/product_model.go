@@ -1,11 +1,22 @@
 package models
 
 // Product represents the products table in the database.
+//
+// Prices can be supplied either as line totals or as unit values; whichever
+// side is missing is derived before the product is validated and stored.
+// Requests bind to ProductRequest and responses are written as ProductResponse.
 type Product struct {
-    ID         int     `json:"id" db:"id" binding:"required"`                   // Unique ID for the product
-    InvoiceNo  string  `json:"invoice_no" db:"invoice_no" binding:"required"`   // Foreign key to invoice
-    ItemName   string  `json:"item_name" db:"item_name" binding:"required,minLength=5"` // Name of the product, minLength: 5
-    Quantity   int     `json:"quantity" db:"quantity" binding:"required,min=1"`   // Product quantity, minValue: 1
-    TotalCost  float64 `json:"total_cost" db:"total_cost" binding:"required,min=0"` // Cost of the product sold, minValue: 0
-    TotalPrice float64 `json:"total_price" db:"total_price" binding:"required,min=0"` // Price of the product sold, minValue: 0
+	ID              int     `db:"id"`               // Unique ID for the product, assigned by the database
+	InvoiceNo       string  `db:"invoice_no"`       // Foreign key to invoice, taken from the invoice
+	ItemName        string  `db:"item_name"`        // Name of the product
+	Quantity        int     `db:"quantity"`         // Product quantity
+	UnitCost        Money   `db:"unit_cost"`        // Cost per unit
+	UnitPrice       Money   `db:"unit_price"`       // Price per unit before discount
+	DiscountAmount  Money   `db:"discount_amount"`  // Discount taken off the line
+	DiscountPercent Percent `db:"discount_percent"` // Discount as a percentage of quantity × unit_price
+	TotalCost       Money   `db:"total_cost"`       // Cost of the product sold
+	TotalPrice      Money   `db:"total_price"`      // Price of the product sold after discount
+	TaxCode         string  `db:"tax_code"`         // Tax code from tax_rates, optional
+	TaxRate         Percent `db:"tax_rate"`         // Rate applied when the line was created
+	TaxAmount       Money   `db:"tax_amount"`       // Tax on total_price, computed on insert
 }
@@ -0,0 +1,141 @@
+package models
+
+import "time"
+
+// InvoiceResponse is an invoice as returned by the API, with the values the server computed.
+type InvoiceResponse struct {
+	ID              int               `json:"id"`
+	InvoiceNo       string            `json:"invoice_no"`
+	Date            time.Time         `json:"date"`
+	CustomerName    string            `json:"customer_name"`
+	SalespersonName string            `json:"salesperson_name"`
+	PaymentType     string            `json:"payment_type"`
+	Notes           string            `json:"notes,omitempty"`
+	Currency        string            `json:"currency"`
+	TaxMode         string            `json:"tax_mode"`
+	TaxTotal        Money             `json:"tax_total"`
+	TotalAmount     Money             `json:"total_amount"`
+	DueDate         *time.Time        `json:"due_date,omitempty"`
+	PaymentTerms    *int              `json:"payment_terms_days,omitempty"`
+	PaidAmount      Money             `json:"paid_amount"`
+	CreditedAmount  Money             `json:"credited_amount"`
+	PaymentStatus   string            `json:"payment_status,omitempty"`
+	Status          string            `json:"status"`
+	IssuedAt        *time.Time        `json:"issued_at,omitempty"`
+	VoidedAt        *time.Time        `json:"voided_at,omitempty"`
+	VoidReason      string            `json:"void_reason,omitempty"`
+	Products        []ProductResponse `json:"products"`
+}
+
+// ProductResponse is a product line as returned by the API.
+type ProductResponse struct {
+	ID              int     `json:"id"`
+	InvoiceNo       string  `json:"invoice_no"`
+	ItemName        string  `json:"item_name"`
+	Quantity        int     `json:"quantity"`
+	UnitCost        Money   `json:"unit_cost"`
+	UnitPrice       Money   `json:"unit_price"`
+	DiscountAmount  Money   `json:"discount_amount"`
+	DiscountPercent Percent `json:"discount_percent"`
+	TotalCost       Money   `json:"total_cost"`
+	TotalPrice      Money   `json:"total_price"`
+	TaxCode         string  `json:"tax_code,omitempty"`
+	TaxRate         Percent `json:"tax_rate"`
+	TaxAmount       Money   `json:"tax_amount"`
+}
+
+// ToInvoice maps a create request onto a new invoice.
+func (r CreateInvoiceRequest) ToInvoice() Invoice {
+	invoice := Invoice{
+		InvoiceNo:       r.InvoiceNo,
+		Date:            r.Date,
+		CustomerName:    r.CustomerName,
+		SalespersonName: r.SalespersonName,
+		PaymentType:     r.PaymentType,
+		Notes:           r.Notes,
+		Currency:        r.Currency,
+		TaxMode:         r.TaxMode,
+		DueDate:         r.DueDate,
+		PaymentTerms:    r.PaymentTerms,
+		Status:          r.Status,
+		Products:        make([]Product, len(r.Products)),
+	}
+	for i, product := range r.Products {
+		invoice.Products[i] = product.ToProduct()
+	}
+	return invoice
+}
+
+// ToProduct maps a product request onto a new product line.
+func (r ProductRequest) ToProduct() Product {
+	return Product{
+		ItemName:        r.ItemName,
+		Quantity:        r.Quantity,
+		UnitCost:        r.UnitCost,
+		UnitPrice:       r.UnitPrice,
+		DiscountAmount:  r.DiscountAmount,
+		DiscountPercent: r.DiscountPercent,
+		TotalCost:       r.TotalCost,
+		TotalPrice:      r.TotalPrice,
+		TaxCode:         r.TaxCode,
+	}
+}
+
+// NewInvoiceResponse maps an invoice and its products onto the API response.
+func NewInvoiceResponse(invoice Invoice) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:              invoice.ID,
+		InvoiceNo:       invoice.InvoiceNo,
+		Date:            invoice.Date,
+		CustomerName:    invoice.CustomerName,
+		SalespersonName: invoice.SalespersonName,
+		PaymentType:     invoice.PaymentType,
+		Notes:           invoice.Notes,
+		Currency:        invoice.Currency,
+		TaxMode:         invoice.TaxMode,
+		TaxTotal:        invoice.TaxTotal,
+		TotalAmount:     invoice.TotalAmount,
+		DueDate:         invoice.DueDate,
+		PaymentTerms:    invoice.PaymentTerms,
+		PaidAmount:      invoice.PaidAmount,
+		CreditedAmount:  invoice.CreditedAmount,
+		PaymentStatus:   invoice.PaymentStatus,
+		Status:          invoice.Status,
+		IssuedAt:        invoice.IssuedAt,
+		VoidedAt:        invoice.VoidedAt,
+		VoidReason:      invoice.VoidReason,
+		Products:        make([]ProductResponse, len(invoice.Products)),
+	}
+	for i, product := range invoice.Products {
+		response.Products[i] = NewProductResponse(product)
+	}
+	return response
+}
+
+// NewInvoiceResponses maps a list of invoices onto API responses.
+func NewInvoiceResponses(invoices []Invoice) []InvoiceResponse {
+	responses := make([]InvoiceResponse, len(invoices))
+	for i, invoice := range invoices {
+		responses[i] = NewInvoiceResponse(invoice)
+	}
+	return responses
+}
+
+// NewProductResponse maps a product line onto the API response.
+func NewProductResponse(product Product) ProductResponse {
+	return ProductResponse{
+		ID:              product.ID,
+		InvoiceNo:       product.InvoiceNo,
+		ItemName:        product.ItemName,
+		Quantity:        product.Quantity,
+		UnitCost:        product.UnitCost,
+		UnitPrice:       product.UnitPrice,
+		DiscountAmount:  product.DiscountAmount,
+		DiscountPercent: product.DiscountPercent,
+		TotalCost:       product.TotalCost,
+		TotalPrice:      product.TotalPrice,
+		TaxCode:         product.TaxCode,
+		TaxRate:         product.TaxRate,
+		TaxAmount:       product.TaxAmount,
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+// Tax modes describe whether a product's total_price excludes or already includes tax.
+const (
+	TaxModeExclusive = "exclusive"
+	TaxModeInclusive = "inclusive"
+)
+
+// TaxRate represents the tax_rates table in the database.
+type TaxRate struct {
+	Code   string  `json:"code" db:"code"`     // Tax code referenced by product lines
+	Name   string  `json:"name" db:"name"`     // Display name
+	Rate   Percent `json:"rate" db:"rate"`     // Rate in percent
+	Active bool    `json:"active" db:"active"` // Inactive codes cannot be used on new invoices
+}
+
+// TaxSummary aggregates the tax of a set of product lines.
+type TaxSummary struct {
+	TaxableAmount Money            `json:"taxable_amount"` // Sales excluding tax
+	TaxAmount     Money            `json:"tax_amount"`     // Tax charged on those sales
+	TotalAmount   Money            `json:"total_amount"`   // Sales including tax
+	ByCode        []TaxCodeSummary `json:"by_code"`        // Breakdown per tax code and rate
+}
+
+// TaxCodeSummary is the part of a TaxSummary charged under one tax code and rate.
+type TaxCodeSummary struct {
+	TaxCode       string  `json:"tax_code"`
+	TaxRate       Percent `json:"tax_rate"`
+	TaxableAmount Money   `json:"taxable_amount"`
+	TaxAmount     Money   `json:"tax_amount"`
+}
+
+// AddLine adds a product line, given its price excluding tax, to the summary.
+func (s *TaxSummary) AddLine(product Product, taxable Money) {
+	s.TaxableAmount += taxable
+	s.TaxAmount += product.TaxAmount
+	s.TotalAmount += taxable + product.TaxAmount
+
+	for i := range s.ByCode {
+		if s.ByCode[i].TaxCode == product.TaxCode && s.ByCode[i].TaxRate == product.TaxRate {
+			s.ByCode[i].TaxableAmount += taxable
+			s.ByCode[i].TaxAmount += product.TaxAmount
+			return
+		}
+	}
+	s.ByCode = append(s.ByCode, TaxCodeSummary{
+		TaxCode:       product.TaxCode,
+		TaxRate:       product.TaxRate,
+		TaxableAmount: taxable,
+		TaxAmount:     product.TaxAmount,
+	})
+}
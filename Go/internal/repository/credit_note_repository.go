@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCreditNoteExists is returned when a credit note number is already taken.
+	ErrCreditNoteExists = errors.New("credit note already exists")
+	// ErrInvalidCreditNote wraps validation failures of a credit note.
+	ErrInvalidCreditNote = errors.New("invalid credit note")
+)
+
+// creditNoteColumns is the column list scanned by scanCreditNotes, including the currency,
+// tax mode and payment method of the original invoice, and whether that method is credit.
+const creditNoteColumns = `c.id, c.credit_note_no, c.invoice_no, c.date, c.reason, i.currency, i.tax_mode, i.payment_type,
+	(SELECT is_credit FROM payment_methods WHERE payment_methods.code = i.payment_type), c.tax_total, c.total_amount`
+
+// GetCreditNotes retrieves the credit notes raised against an invoice, oldest first.
+func GetCreditNotes(q Querier, invoiceNo string) ([]models.CreditNote, error) {
+	sqlQuery := `SELECT ` + creditNoteColumns + `
+	             FROM credit_notes c
+	             JOIN invoices i ON i.invoice_no = c.invoice_no
+	             WHERE c.invoice_no = $1
+	             ORDER BY c.date, c.id`
+	return scanCreditNotes(q, sqlQuery, invoiceNo)
+}
+
+// GetCreditNotesForInvoices retrieves the credit notes raised against any of the invoices.
+func GetCreditNotesForInvoices(q Querier, invoiceNos []string) ([]models.CreditNote, error) {
+	sqlQuery := `SELECT ` + creditNoteColumns + `
+	             FROM credit_notes c
+	             JOIN invoices i ON i.invoice_no = c.invoice_no
+	             WHERE c.invoice_no = ANY($1)
+	             ORDER BY c.date, c.id`
+	return scanCreditNotes(q, sqlQuery, pq.Array(invoiceNos))
+}
+
+// GetCreditNotesByDateRange retrieves the credit notes dated between from and to, inclusive.
+func GetCreditNotesByDateRange(q Querier, from, to time.Time) ([]models.CreditNote, error) {
+	sqlQuery := `SELECT ` + creditNoteColumns + `
+	             FROM credit_notes c
+	             JOIN invoices i ON i.invoice_no = c.invoice_no
+	             WHERE c.date BETWEEN $1 AND $2
+	             ORDER BY c.date, c.id`
+	return scanCreditNotes(q, sqlQuery, from, to)
+}
+
+// scanCreditNotes runs a query selecting creditNoteColumns and loads the lines of each credit note.
+func scanCreditNotes(q Querier, sqlQuery string, args ...interface{}) ([]models.CreditNote, error) {
+	rows, err := q.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creditNotes := []models.CreditNote{}
+	for rows.Next() {
+		var creditNote models.CreditNote
+		if err := rows.Scan(&creditNote.ID, &creditNote.CreditNoteNo, &creditNote.InvoiceNo, &creditNote.Date, &creditNote.Reason,
+			&creditNote.Currency, &creditNote.TaxMode, &creditNote.PaymentType, &creditNote.IsCredit, &creditNote.TaxTotal, &creditNote.TotalAmount); err != nil {
+			return nil, err
+		}
+		creditNotes = append(creditNotes, creditNote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the lines of each credit note
+	for i := range creditNotes {
+		creditNotes[i].Lines, err = getCreditNoteLines(q, creditNotes[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return creditNotes, nil
+}
+
+// getCreditNoteLines retrieves the lines of a credit note along with the name and tax code of the original product.
+func getCreditNoteLines(q Querier, creditNoteID int) ([]models.CreditNoteLine, error) {
+	sqlQuery := `SELECT l.id, l.product_id, p.item_name, l.quantity, l.total_cost, l.total_price, COALESCE(p.tax_code, ''), p.tax_rate, l.tax_amount
+	             FROM credit_note_lines l
+	             JOIN products p ON p.id = l.product_id
+	             WHERE l.credit_note_id = $1
+	             ORDER BY l.id`
+	rows, err := q.Query(sqlQuery, creditNoteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.CreditNoteLine{}
+	for rows.Next() {
+		var line models.CreditNoteLine
+		if err := rows.Scan(&line.ID, &line.ProductID, &line.ItemName, &line.Quantity, &line.TotalCost, &line.TotalPrice,
+			&line.TaxCode, &line.TaxRate, &line.TaxAmount); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// CreateCreditNote records goods returned against an issued invoice and sets the IDs and amounts
+// of the credit note and its lines. Each line may not return more than is left of the product line
+// after earlier credit notes. It returns sql.ErrNoRows if the invoice doesn't exist.
+func CreateCreditNote(db *sql.DB, creditNote *models.CreditNote) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the invoice so concurrent credit notes can't return the same goods twice
+	var status string
+	var invoiceDate time.Time
+	err = tx.QueryRow(`SELECT status, date, currency, tax_mode, payment_type FROM invoices WHERE invoice_no = $1 FOR UPDATE`, creditNote.InvoiceNo).
+		Scan(&status, &invoiceDate, &creditNote.Currency, &creditNote.TaxMode, &creditNote.PaymentType)
+	if err != nil {
+		return err
+	}
+	if status != models.InvoiceStatusIssued {
+		return fmt.Errorf("%w: credit notes can only be raised against issued invoices", ErrInvalidCreditNote)
+	}
+	if utils.DaysBetween(invoiceDate, creditNote.Date) < 0 {
+		return fmt.Errorf("%w: date cannot be before the invoice date", ErrInvalidCreditNote)
+	}
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM credit_notes WHERE credit_note_no = $1)`, creditNote.CreditNoteNo).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrCreditNoteExists
+	}
+
+	creditNote.TaxTotal = 0
+	seen := map[int]bool{}
+	for i := range creditNote.Lines {
+		line := &creditNote.Lines[i]
+		if seen[line.ProductID] {
+			return fmt.Errorf("%w: product %d appears more than once", ErrInvalidCreditNote, line.ProductID)
+		}
+		seen[line.ProductID] = true
+
+		product, credited, err := getCreditableProduct(tx, creditNote.InvoiceNo, line.ProductID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: product %d is not on invoice %s", ErrInvalidCreditNote, line.ProductID, creditNote.InvoiceNo)
+		}
+		if err != nil {
+			return err
+		}
+		if err := utils.ProrateCreditNoteLine(line, product, credited); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCreditNote, err)
+		}
+		creditNote.TaxTotal += line.TaxAmount
+	}
+	creditNote.TotalAmount = utils.CreditNoteTotalAmount(*creditNote)
+
+	sqlQuery := `INSERT INTO credit_notes (credit_note_no, invoice_no, date, reason, tax_total, total_amount)
+	             VALUES ($1, $2, $3, $4, $5, $6)
+	             RETURNING id`
+	err = tx.QueryRow(sqlQuery, creditNote.CreditNoteNo, creditNote.InvoiceNo, creditNote.Date, creditNote.Reason,
+		creditNote.TaxTotal, creditNote.TotalAmount).Scan(&creditNote.ID)
+	if err != nil {
+		return err
+	}
+
+	lineQuery := `INSERT INTO credit_note_lines (credit_note_id, product_id, quantity, total_cost, total_price, tax_amount)
+	              VALUES ($1, $2, $3, $4, $5, $6)
+	              RETURNING id`
+	for i := range creditNote.Lines {
+		line := &creditNote.Lines[i]
+		err = tx.QueryRow(lineQuery, creditNote.ID, line.ProductID, line.Quantity, line.TotalCost, line.TotalPrice, line.TaxAmount).
+			Scan(&line.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// getCreditableProduct loads a product line of an invoice along with what earlier credit notes already took off it.
+func getCreditableProduct(q Querier, invoiceNo string, productID int) (product models.Product, credited models.CreditNoteLine, err error) {
+	sqlQuery := `SELECT p.id, p.item_name, p.quantity, p.total_cost, p.total_price, COALESCE(p.tax_code, ''), p.tax_rate, p.tax_amount,
+	                    COALESCE(SUM(l.quantity), 0), COALESCE(SUM(l.total_cost), 0), COALESCE(SUM(l.total_price), 0), COALESCE(SUM(l.tax_amount), 0)
+	             FROM products p
+	             LEFT JOIN credit_note_lines l ON l.product_id = p.id
+	             WHERE p.id = $1 AND p.invoice_no = $2
+	             GROUP BY p.id`
+	err = q.QueryRow(sqlQuery, productID, invoiceNo).
+		Scan(&product.ID, &product.ItemName, &product.Quantity, &product.TotalCost, &product.TotalPrice, &product.TaxCode, &product.TaxRate, &product.TaxAmount,
+			&credited.Quantity, &credited.TotalCost, &credited.TotalPrice, &credited.TaxAmount)
+	return product, credited, err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+var (
+	// ErrExchangeRateNotFound is returned when no rate exists for a currency on or before a date.
+	ErrExchangeRateNotFound = errors.New("exchange rate not found")
+	// ErrExchangeRateExists is returned when a currency already has a rate on the same date.
+	ErrExchangeRateExists = errors.New("exchange rate already exists for this currency and date")
+)
+
+// GetExchangeRates retrieves the rates into the base currency, newest first, optionally for one currency.
+func GetExchangeRates(db *sql.DB, baseCurrency, currency string) ([]models.ExchangeRate, error) {
+	sqlQuery := `SELECT id, base_currency, currency, rate_date, rate
+	             FROM exchange_rates
+	             WHERE base_currency = $1 AND ($2 = '' OR currency = $2)
+	             ORDER BY currency, rate_date DESC`
+	rows, err := db.Query(sqlQuery, baseCurrency, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exchangeRates := []models.ExchangeRate{}
+	for rows.Next() {
+		var exchangeRate models.ExchangeRate
+		if err := rows.Scan(&exchangeRate.ID, &exchangeRate.BaseCurrency, &exchangeRate.Currency, &exchangeRate.RateDate, &exchangeRate.Rate); err != nil {
+			return nil, err
+		}
+		exchangeRates = append(exchangeRates, exchangeRate)
+	}
+	return exchangeRates, rows.Err()
+}
+
+// GetExchangeRate retrieves a single exchange rate by ID. It returns sql.ErrNoRows if the ID doesn't exist.
+func GetExchangeRate(db *sql.DB, id int) (models.ExchangeRate, error) {
+	var exchangeRate models.ExchangeRate
+	sqlQuery := `SELECT id, base_currency, currency, rate_date, rate FROM exchange_rates WHERE id = $1`
+	err := db.QueryRow(sqlQuery, id).
+		Scan(&exchangeRate.ID, &exchangeRate.BaseCurrency, &exchangeRate.Currency, &exchangeRate.RateDate, &exchangeRate.Rate)
+	return exchangeRate, err
+}
+
+// FindExchangeRate returns the rate for currency that applies on date: the latest one dated on or before it.
+func FindExchangeRate(q Querier, baseCurrency, currency string, date time.Time) (models.ExchangeRate, error) {
+	var exchangeRate models.ExchangeRate
+	sqlQuery := `SELECT id, base_currency, currency, rate_date, rate
+	             FROM exchange_rates
+	             WHERE base_currency = $1 AND currency = $2 AND rate_date <= $3
+	             ORDER BY rate_date DESC
+	             LIMIT 1`
+	err := q.QueryRow(sqlQuery, baseCurrency, currency, date).
+		Scan(&exchangeRate.ID, &exchangeRate.BaseCurrency, &exchangeRate.Currency, &exchangeRate.RateDate, &exchangeRate.Rate)
+	if err == sql.ErrNoRows {
+		return exchangeRate, fmt.Errorf("%w: %s to %s on %s", ErrExchangeRateNotFound, currency, baseCurrency, date.Format("2006-01-02"))
+	}
+	return exchangeRate, err
+}
+
+// CreateExchangeRate inserts a new exchange rate and sets its ID.
+// It returns ErrExchangeRateExists if the currency already has a rate on that date.
+func CreateExchangeRate(db *sql.DB, exchangeRate *models.ExchangeRate) error {
+	sqlQuery := `INSERT INTO exchange_rates (base_currency, currency, rate_date, rate) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := db.QueryRow(sqlQuery, exchangeRate.BaseCurrency, exchangeRate.Currency, exchangeRate.RateDate, exchangeRate.Rate).
+		Scan(&exchangeRate.ID)
+	if isUniqueViolation(err) {
+		return ErrExchangeRateExists
+	}
+	return err
+}
+
+// UpdateExchangeRate updates the currency, date and rate of an exchange rate.
+// It returns ErrExchangeRateExists if the currency already has another rate on that date.
+func UpdateExchangeRate(db *sql.DB, exchangeRate models.ExchangeRate) error {
+	sqlQuery := `UPDATE exchange_rates SET base_currency = $1, currency = $2, rate_date = $3, rate = $4 WHERE id = $5`
+	result, err := db.Exec(sqlQuery, exchangeRate.BaseCurrency, exchangeRate.Currency, exchangeRate.RateDate, exchangeRate.Rate, exchangeRate.ID)
+	if isUniqueViolation(err) {
+		return ErrExchangeRateExists
+	}
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteExchangeRate removes an exchange rate by ID.
+func DeleteExchangeRate(db *sql.DB, id int) error {
+	result, err := db.Exec(`DELETE FROM exchange_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Converter converts invoice amounts into the base currency at the rate on each invoice date.
+// Rates are cached per currency and day, so converting many invoices costs one query per pair.
+type Converter struct {
+	q            Querier
+	BaseCurrency string
+	rates        map[string]models.Rate
+}
+
+// NewConverter creates a Converter into baseCurrency.
+func NewConverter(q Querier, baseCurrency string) *Converter {
+	return &Converter{q: q, BaseCurrency: baseCurrency, rates: map[string]models.Rate{}}
+}
+
+// Rate returns the rate from currency into the base currency on date.
+func (c *Converter) Rate(currency string, date time.Time) (models.Rate, error) {
+	if currency == c.BaseCurrency {
+		return models.RateOne, nil
+	}
+
+	key := currency + date.Format("2006-01-02")
+	if rate, ok := c.rates[key]; ok {
+		return rate, nil
+	}
+	exchangeRate, err := FindExchangeRate(c.q, c.BaseCurrency, currency, date)
+	if err != nil {
+		return 0, err
+	}
+	c.rates[key] = exchangeRate.Rate
+	return exchangeRate.Rate, nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"widatech-technical-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrImportJobFinished is returned when cancelling an import job that has already finished.
+var ErrImportJobFinished = errors.New("import job has already finished")
+
+const importJobColumns = `id, status, format, dry_run, atomic, profile, on_conflict, processed, failed, errors, result, error, cancel_requested,
+                          created_at, started_at, finished_at`
+
+// CreateImportJob queues an import job with its uploaded files and sets its ID, status and creation time.
+func CreateImportJob(db *sql.DB, job *models.ImportJob, files []models.ImportJobFile) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sqlQuery := `INSERT INTO import_jobs (format, dry_run, atomic, profile, on_conflict)
+	             VALUES ($1, $2, $3, $4, $5)
+	             RETURNING id, status, created_at`
+	err = tx.QueryRow(sqlQuery, job.Format, job.DryRun, job.Atomic, job.Profile, job.OnConflict).Scan(&job.ID, &job.Status, &job.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		_, err = tx.Exec(`INSERT INTO import_job_files (job_id, field, name, content) VALUES ($1, $2, $3, $4)`,
+			job.ID, file.Field, file.Name, file.Content)
+		if err != nil {
+			return err
+		}
+	}
+	job.Errors = []models.ImportError{}
+	return tx.Commit()
+}
+
+// GetImportJob retrieves an import job. It returns sql.ErrNoRows if the ID doesn't exist.
+func GetImportJob(db *sql.DB, id int) (models.ImportJob, error) {
+	return scanImportJob(db.QueryRow(`SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id))
+}
+
+// GetImportJobFiles retrieves the files uploaded for an import job.
+func GetImportJobFiles(db *sql.DB, id int) ([]models.ImportJobFile, error) {
+	rows, err := db.Query(`SELECT field, name, content FROM import_job_files WHERE job_id = $1 ORDER BY field`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []models.ImportJobFile{}
+	for rows.Next() {
+		var file models.ImportJobFile
+		if err := rows.Scan(&file.Field, &file.Name, &file.Content); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// ClaimImportJob marks the oldest queued import job as running on the given server and returns it.
+// Concurrent workers skip each other's claims. It returns sql.ErrNoRows when no job is queued.
+func ClaimImportJob(db *sql.DB, owner string) (models.ImportJob, error) {
+	sqlQuery := `UPDATE import_jobs
+	             SET status = 'running', started_at = NOW(), owner = $1
+	             WHERE id = (SELECT id FROM import_jobs WHERE status = 'queued' ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED)
+	             RETURNING ` + importJobColumns
+	return scanImportJob(db.QueryRow(sqlQuery, owner))
+}
+
+// UpdateImportJobProgress records the rows a running job has processed so far, and reports whether
+// the job has been asked to cancel.
+func UpdateImportJobProgress(db *sql.DB, id, processed, failed int) (cancelRequested bool, err error) {
+	err = db.QueryRow(`UPDATE import_jobs SET processed = $1, failed = $2 WHERE id = $3 RETURNING cancel_requested`,
+		processed, failed, id).Scan(&cancelRequested)
+	return cancelRequested, err
+}
+
+// ImportJobCancelRequested reports whether a job has been asked to cancel.
+func ImportJobCancelRequested(db *sql.DB, id int) (cancelRequested bool, err error) {
+	err = db.QueryRow(`SELECT cancel_requested FROM import_jobs WHERE id = $1`, id).Scan(&cancelRequested)
+	return cancelRequested, err
+}
+
+// FinishImportJob records the outcome of an import job: its final status, counts, errors and result.
+func FinishImportJob(db *sql.DB, job *models.ImportJob) error {
+	if job.Errors == nil {
+		job.Errors = []models.ImportError{}
+	}
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+	// Passed as strings: lib/pq would send a []byte as bytea
+	var result sql.NullString
+	if job.Result != nil {
+		encoded, err := json.Marshal(job.Result)
+		if err != nil {
+			return err
+		}
+		result = sql.NullString{String: string(encoded), Valid: true}
+	}
+	sqlQuery := `UPDATE import_jobs
+	             SET status = $1, processed = $2, failed = $3, errors = $4, result = $5, error = $6, finished_at = NOW()
+	             WHERE id = $7
+	             RETURNING finished_at`
+	return db.QueryRow(sqlQuery, job.Status, job.Processed, job.Failed, string(errs), result, job.Error, job.ID).Scan(&job.FinishedAt)
+}
+
+// CancelImportJob cancels a queued import job right away, and asks a running one to stop after its current row.
+// It returns sql.ErrNoRows if the ID doesn't exist, or ErrImportJobFinished if the job is no longer queued or running.
+func CancelImportJob(db *sql.DB, id int) (models.ImportJob, error) {
+	sqlQuery := `UPDATE import_jobs
+	             SET cancel_requested = TRUE,
+	                 status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+	                 finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END
+	             WHERE id = $1 AND status IN ('queued', 'running')
+	             RETURNING ` + importJobColumns
+	job, err := scanImportJob(db.QueryRow(sqlQuery, id))
+	if err == sql.ErrNoRows {
+		if _, err := GetImportJob(db, id); err != nil {
+			return job, err
+		}
+		return job, ErrImportJobFinished
+	}
+	return job, err
+}
+
+// FailInterruptedImportJobs marks the jobs the given servers left running when they stopped as failed.
+func FailInterruptedImportJobs(db *sql.DB, owners []string) error {
+	_, err := db.Exec(`UPDATE import_jobs
+	                   SET status = 'failed', error = 'the server stopped while the job was running', finished_at = NOW()
+	                   WHERE status = 'running' AND owner = ANY($1)`, pq.Array(owners))
+	return err
+}
+
+// BeatImportInstance records that a server is running.
+func BeatImportInstance(db *sql.DB, id string) error {
+	_, err := db.Exec(`INSERT INTO import_instances (id) VALUES ($1)
+	                   ON CONFLICT (id) DO UPDATE SET heartbeat_at = NOW()`, id)
+	return err
+}
+
+// GetStoppedImportInstances returns the servers that haven't recorded they are running for staleAfter,
+// and "", which owns the jobs and rows of servers from before they were recorded.
+func GetStoppedImportInstances(db *sql.DB, staleAfter time.Duration) ([]string, error) {
+	rows, err := db.Query(`SELECT id FROM import_instances WHERE heartbeat_at < NOW() - make_interval(secs => $1)`, staleAfter.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{""}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteImportInstances forgets the given servers once their jobs and rows are cleaned up, unless
+// they recorded they are running again meanwhile.
+func DeleteImportInstances(db *sql.DB, ids []string, staleAfter time.Duration) error {
+	_, err := db.Exec(`DELETE FROM import_instances WHERE id = ANY($1) AND heartbeat_at < NOW() - make_interval(secs => $2)`,
+		pq.Array(ids), staleAfter.Seconds())
+	return err
+}
+
+// scanImportJob scans a row of importJobColumns, decoding the JSONB errors and result.
+func scanImportJob(row interface{ Scan(...any) error }) (models.ImportJob, error) {
+	var job models.ImportJob
+	var errs, result []byte
+	err := row.Scan(&job.ID, &job.Status, &job.Format, &job.DryRun, &job.Atomic, &job.Profile, &job.OnConflict, &job.Processed, &job.Failed,
+		&errs, &result, &job.Error, &job.CancelRequested, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return job, err
+	}
+	if err := json.Unmarshal(errs, &job.Errors); err != nil {
+		return job, err
+	}
+	if result != nil {
+		if err := json.Unmarshal(result, &job.Result); err != nil {
+			return job, err
+		}
+	}
+	return job, nil
+}
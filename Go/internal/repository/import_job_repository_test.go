@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// TestCleanUpStoppedInstances checks that only the server that stopped recording it is running is
+// taken for stopped, and that cleaning up after it leaves the jobs and staged rows of a live server alone.
+func TestCleanUpStoppedInstances(t *testing.T) {
+	db := testDB(t)
+	suffix := time.Now().UnixNano()
+	live, stopped := fmt.Sprintf("TEST-live-%d", suffix), fmt.Sprintf("TEST-stopped-%d", suffix)
+	instances := []string{live, stopped}
+	jobs := map[string]int{}
+	stagings := map[string]int64{}
+	t.Cleanup(func() {
+		for _, instance := range instances {
+			db.Exec(`DELETE FROM import_jobs WHERE id = $1`, jobs[instance])
+			db.Exec(`DELETE FROM import_staging_rows WHERE import_id = $1`, stagings[instance])
+		}
+		db.Exec(`DELETE FROM import_instances WHERE id = ANY($1)`, pq.Array(instances))
+	})
+
+	for _, instance := range instances {
+		if err := BeatImportInstance(db, instance); err != nil {
+			t.Fatal(err)
+		}
+		job := models.ImportJob{Format: models.ImportFormatXLSX, OnConflict: models.ImportConflictError}
+		if err := CreateImportJob(db, &job, nil); err != nil {
+			t.Fatal(err)
+		}
+		jobs[instance] = job.ID
+		if _, err := db.Exec(`UPDATE import_jobs SET status = 'running', owner = $1 WHERE id = $2`, instance, job.ID); err != nil {
+			t.Fatal(err)
+		}
+		staging, err := StartImportStaging(db, instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stagings[instance] = staging.ID
+		if err := staging.Add(models.ImportStagedInvoices, models.ImportStagedRow{Row: 2, InvoiceNo: "INV-1", Cells: []string{"INV-1"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := staging.Finish(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE import_instances SET heartbeat_at = NOW() - INTERVAL '2 minutes' WHERE id = $1`, stopped); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := GetStoppedImportInstances(db, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := map[string]bool{}
+	for _, id := range found {
+		taken[id] = true
+	}
+	if !taken[stopped] || taken[live] {
+		t.Fatalf("GetStoppedImportInstances = %v, want %s without %s", found, stopped, live)
+	}
+
+	// The other servers' leftovers found above aren't this test's to clean up
+	if err := FailInterruptedImportJobs(db, []string{stopped}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ClearImportStaging(db, []string{stopped}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteImportInstances(db, instances, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for instance, status := range map[string]string{live: models.ImportJobRunning, stopped: models.ImportJobFailed} {
+		job, err := GetImportJob(db, jobs[instance])
+		if err != nil || job.Status != status {
+			t.Errorf("job of %s is %q, %v, want %s", instance, job.Status, err, status)
+		}
+	}
+	for instance, want := range map[string]int{live: 1, stopped: 0} {
+		rows, err := GetStagedRows(db, stagings[instance], models.ImportStagedInvoices, 0, 10)
+		if err != nil || len(rows) != want {
+			t.Errorf("%s has %d staged rows, %v, want %d", instance, len(rows), err, want)
+		}
+	}
+	var remaining []string
+	if err := db.QueryRow(`SELECT ARRAY(SELECT id FROM import_instances WHERE id = ANY($1))`, pq.Array(instances)).
+		Scan(pq.Array(&remaining)); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] != live {
+		t.Errorf("instances left %v, want only %s", remaining, live)
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"widatech-technical-challenge/internal/models"
+)
+
+// ErrImportProfileExists is returned when creating an import profile whose name is already taken.
+var ErrImportProfileExists = errors.New("import profile already exists")
+
+const importProfileColumns = `name, invoice_sheet, product_sheet, columns, date_format, decimal_separator, defaults, updated_at`
+
+// GetImportProfiles retrieves all import profiles ordered by name.
+func GetImportProfiles(db *sql.DB) ([]models.ImportProfile, error) {
+	rows, err := db.Query(`SELECT ` + importProfileColumns + ` FROM import_profiles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []models.ImportProfile{}
+	for rows.Next() {
+		profile, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// GetImportProfile retrieves a single import profile. It returns sql.ErrNoRows if the name doesn't exist.
+func GetImportProfile(db *sql.DB, name string) (models.ImportProfile, error) {
+	return scanImportProfile(db.QueryRow(`SELECT `+importProfileColumns+` FROM import_profiles WHERE name = $1`, name))
+}
+
+// CreateImportProfile inserts a new import profile.
+func CreateImportProfile(db *sql.DB, profile *models.ImportProfile) error {
+	columns, defaults, err := marshalImportProfileMaps(*profile)
+	if err != nil {
+		return err
+	}
+	sqlQuery := `INSERT INTO import_profiles (name, invoice_sheet, product_sheet, columns, date_format, decimal_separator, defaults)
+	             VALUES ($1, $2, $3, $4, $5, $6, $7)
+	             ON CONFLICT (name) DO NOTHING
+	             RETURNING updated_at`
+	err = db.QueryRow(sqlQuery, profile.Name, profile.InvoiceSheet, profile.ProductSheet, columns, profile.DateFormat,
+		profile.DecimalSeparator, defaults).Scan(&profile.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrImportProfileExists
+	}
+	return err
+}
+
+// UpdateImportProfile replaces the settings of an import profile.
+func UpdateImportProfile(db *sql.DB, profile *models.ImportProfile) error {
+	columns, defaults, err := marshalImportProfileMaps(*profile)
+	if err != nil {
+		return err
+	}
+	sqlQuery := `UPDATE import_profiles
+	             SET invoice_sheet = $1, product_sheet = $2, columns = $3, date_format = $4, decimal_separator = $5, defaults = $6, updated_at = NOW()
+	             WHERE name = $7
+	             RETURNING updated_at`
+	return db.QueryRow(sqlQuery, profile.InvoiceSheet, profile.ProductSheet, columns, profile.DateFormat,
+		profile.DecimalSeparator, defaults, profile.Name).Scan(&profile.UpdatedAt)
+}
+
+// DeleteImportProfile removes an import profile.
+func DeleteImportProfile(db *sql.DB, name string) error {
+	result, err := db.Exec(`DELETE FROM import_profiles WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// scanImportProfile scans a row of importProfileColumns, decoding the JSONB maps.
+func scanImportProfile(row interface{ Scan(...any) error }) (models.ImportProfile, error) {
+	var profile models.ImportProfile
+	var columns, defaults []byte
+	err := row.Scan(&profile.Name, &profile.InvoiceSheet, &profile.ProductSheet, &columns, &profile.DateFormat,
+		&profile.DecimalSeparator, &defaults, &profile.UpdatedAt)
+	if err != nil {
+		return profile, err
+	}
+	if err := json.Unmarshal(columns, &profile.Columns); err != nil {
+		return profile, err
+	}
+	if err := json.Unmarshal(defaults, &profile.Defaults); err != nil {
+		return profile, err
+	}
+	return profile, nil
+}
+
+// marshalImportProfileMaps encodes the column and default maps of a profile for their JSONB columns.
+// They are passed as strings: lib/pq would send a []byte as bytea.
+func marshalImportProfileMaps(profile models.ImportProfile) (columns, defaults string, err error) {
+	if profile.Columns == nil {
+		profile.Columns = map[string]string{}
+	}
+	if profile.Defaults == nil {
+		profile.Defaults = map[string]string{}
+	}
+	encodedColumns, err := json.Marshal(profile.Columns)
+	if err != nil {
+		return "", "", err
+	}
+	encodedDefaults, err := json.Marshal(profile.Defaults)
+	return string(encodedColumns), string(encodedDefaults), err
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"widatech-technical-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const importStagingColumns = `row_no, invoice_no, cells, raw`
+
+// ImportStaging copies the rows of a file being imported into import_staging_rows as they are read,
+// in a single COPY, so the file is never held in memory.
+type ImportStaging struct {
+	ID    int64
+	owner string
+	tx    *sql.Tx
+	stmt  *sql.Stmt
+}
+
+// StartImportStaging starts staging the rows of a file under a new import ID, owned by the given server.
+func StartImportStaging(db *sql.DB, owner string) (*ImportStaging, error) {
+	staging := &ImportStaging{owner: owner}
+	if err := db.QueryRow(`SELECT nextval('import_staging_seq')`).Scan(&staging.ID); err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.Prepare(pq.CopyIn("import_staging_rows", "import_id", "owner", "sheet", "row_no", "invoice_no", "cells", "raw"))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	staging.tx, staging.stmt = tx, stmt
+	return staging, nil
+}
+
+// Add stages a row of the file as a row of the given sheet.
+func (s *ImportStaging) Add(sheet string, row models.ImportStagedRow) error {
+	cells := stagedText(row.Cells)
+	if cells == nil {
+		cells = []string{}
+	}
+	_, err := s.stmt.Exec(s.ID, s.owner, sheet, row.Row, stagedValue(row.InvoiceNo), pq.Array(cells), pq.Array(stagedText(row.Raw)))
+	return err
+}
+
+// Finish writes the rows staged so far, making them visible to the queries below.
+func (s *ImportStaging) Finish() error {
+	if _, err := s.stmt.Exec(); err != nil {
+		s.Abort()
+		return err
+	}
+	if err := s.stmt.Close(); err != nil {
+		s.tx.Rollback()
+		return err
+	}
+	return s.tx.Commit()
+}
+
+// Abort drops the rows staged so far, when the file can't be read to the end.
+func (s *ImportStaging) Abort() {
+	s.stmt.Close()
+	s.tx.Rollback()
+}
+
+// GetStagedRows retrieves up to limit staged rows of a sheet after the given row, in row order.
+func GetStagedRows(q Querier, importID int64, sheet string, after, limit int) ([]models.ImportStagedRow, error) {
+	sqlQuery := `SELECT ` + importStagingColumns + ` FROM import_staging_rows
+	             WHERE import_id = $1 AND sheet = $2 AND row_no > $3
+	             ORDER BY row_no
+	             LIMIT $4`
+	return scanStagedRows(q.Query(sqlQuery, importID, sheet, after, limit))
+}
+
+// GetStagedProducts retrieves the staged product rows of the given invoice numbers, in row order.
+func GetStagedProducts(q Querier, importID int64, invoiceNos []string) ([]models.ImportStagedRow, error) {
+	sqlQuery := `SELECT ` + importStagingColumns + ` FROM import_staging_rows
+	             WHERE import_id = $1 AND sheet = 'products' AND invoice_no = ANY($2)
+	             ORDER BY row_no`
+	return scanStagedRows(q.Query(sqlQuery, importID, pq.Array(invoiceNos)))
+}
+
+// GetStagedInvoiceRows retrieves the rows of the invoice sheet that each of the given invoice numbers
+// is on, in row order.
+func GetStagedInvoiceRows(q Querier, importID int64, invoiceNos []string) (map[string][]int, error) {
+	sqlQuery := `SELECT invoice_no, row_no FROM import_staging_rows
+	             WHERE import_id = $1 AND sheet = 'invoices' AND invoice_no = ANY($2)
+	             ORDER BY row_no`
+	rows, err := q.Query(sqlQuery, importID, pq.Array(invoiceNos))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoiceRows := map[string][]int{}
+	for rows.Next() {
+		var invoiceNo string
+		var row int
+		if err := rows.Scan(&invoiceNo, &row); err != nil {
+			return nil, err
+		}
+		invoiceRows[invoiceNo] = append(invoiceRows[invoiceNo], row)
+	}
+	return invoiceRows, rows.Err()
+}
+
+// GetStagedOrphans retrieves up to limit staged product rows whose invoice number is on no invoice row,
+// in row order, with how many there are in all.
+func GetStagedOrphans(q Querier, importID int64, limit int) ([]models.ImportStagedRow, int, error) {
+	sqlQuery := `SELECT p.row_no, p.invoice_no, p.cells, p.raw, COUNT(*) OVER ()
+	             FROM import_staging_rows p
+	             WHERE p.import_id = $1 AND p.sheet = 'products'
+	               AND NOT EXISTS (SELECT 1 FROM import_staging_rows i
+	                               WHERE i.import_id = p.import_id AND i.sheet = 'invoices' AND i.invoice_no = p.invoice_no)
+	             ORDER BY p.row_no
+	             LIMIT $2`
+	rows, err := q.Query(sqlQuery, importID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orphans := []models.ImportStagedRow{}
+	total := 0
+	for rows.Next() {
+		var row models.ImportStagedRow
+		if err := rows.Scan(&row.Row, &row.InvoiceNo, pq.Array(&row.Cells), pq.Array(&row.Raw), &total); err != nil {
+			return nil, 0, err
+		}
+		orphans = append(orphans, row)
+	}
+	return orphans, total, rows.Err()
+}
+
+// DeleteStagedRepeatedInvoices keeps only the first staged invoice row of each invoice number, for a
+// file whose invoices are spread over several rows.
+func DeleteStagedRepeatedInvoices(db *sql.DB, importID int64) error {
+	_, err := db.Exec(`DELETE FROM import_staging_rows s
+	                   WHERE s.import_id = $1 AND s.sheet = 'invoices' AND s.invoice_no <> ''
+	                     AND EXISTS (SELECT 1 FROM import_staging_rows f
+	                                 WHERE f.import_id = s.import_id AND f.sheet = 'invoices'
+	                                   AND f.invoice_no = s.invoice_no AND f.row_no < s.row_no)`, importID)
+	return err
+}
+
+// DeleteImportStaging deletes the rows staged by an import once it has ended.
+func DeleteImportStaging(db *sql.DB, importID int64) error {
+	_, err := db.Exec(`DELETE FROM import_staging_rows WHERE import_id = $1`, importID)
+	return err
+}
+
+// ClearImportStaging deletes the rows staged by the given servers, which stopped with their imports unfinished.
+func ClearImportStaging(db *sql.DB, owners []string) error {
+	_, err := db.Exec(`DELETE FROM import_staging_rows WHERE owner = ANY($1)`, pq.Array(owners))
+	return err
+}
+
+// stagedText returns values PostgreSQL can store as text, with stagedValue.
+func stagedText(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	text := make([]string, len(values))
+	for i, value := range values {
+		text[i] = stagedValue(value)
+	}
+	return text
+}
+
+// stagedValue returns a value PostgreSQL can store as text: invalid UTF-8, as in a CSV file saved in
+// another encoding, is replaced by U+FFFD, and NUL bytes are dropped.
+func stagedValue(value string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(value, "\uFFFD"), "\x00", "")
+}
+
+// scanStagedRows scans the rows of a query of importStagingColumns.
+func scanStagedRows(rows *sql.Rows, err error) ([]models.ImportStagedRow, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	staged := []models.ImportStagedRow{}
+	for rows.Next() {
+		var row models.ImportStagedRow
+		if err := rows.Scan(&row.Row, &row.InvoiceNo, pq.Array(&row.Cells), pq.Array(&row.Raw)); err != nil {
+			return nil, err
+		}
+		staged = append(staged, row)
+	}
+	return staged, rows.Err()
+}
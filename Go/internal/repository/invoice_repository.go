@@ -5,122 +5,792 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 	"widatech-technical-challenge/internal/models"
 	"widatech-technical-challenge/utils"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrInvalidInvoice wraps validation failures of an invoice or its products.
+	ErrInvalidInvoice = errors.New("invalid invoice")
+	// ErrInvoiceExists is returned when creating an invoice whose number is already taken.
+	ErrInvoiceExists = errors.New("duplicate invoice number")
+	// ErrInvoiceNotEditable is returned when changing an invoice or its products beyond what its status allows.
+	ErrInvoiceNotEditable = errors.New("invoice is not editable")
+	// ErrInvalidStatusTransition is returned when the workflow doesn't allow an invoice's status change.
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
 )
 
-// CreateInvoice inserts a new invoice record into the database.
-func CreateInvoice(db *sql.DB, invoice models.Invoice) error {
+// ValidationError is an ErrInvalidInvoice that carries the issues the invoice was rejected for,
+// so callers can point at the fields involved.
+type ValidationError struct {
+	Issues []models.Issue
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidInvoice, utils.IssuesError(e.Issues))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInvoice
+}
+
+// CreateInvoice inserts a new invoice record into the database and fills in the values the server computes.
+// Without an invoice_no, the next number of the invoice sequence for the invoice date is used.
+// It returns the warnings raised by the validation rules.
+func CreateInvoice(db *sql.DB, invoice *models.Invoice) ([]models.Issue, error) {
+	var err error
+	if invoice.InvoiceNo == "" && !invoice.Date.IsZero() {
+		if invoice.InvoiceNo, err = NextNumber(db, models.SequenceInvoice, invoice.Date); err != nil {
+			return nil, err
+		}
+	}
+
 	// Start a transaction
 	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Validate the Invoice Fields before proceeding.
-	if err = utils.ValidateInvoiceFields(invoice); err != nil {
-		return err
+	warnings, err := InsertInvoice(tx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// InsertInvoice validates and inserts an invoice and its products inside tx, as CreateInvoice does,
+// leaving the commit to the caller so several invoices can share a transaction. The invoice must
+// already be numbered.
+func InsertInvoice(tx *sql.Tx, invoice *models.Invoice) ([]models.Issue, error) {
+	_, issues, err := SaveInvoice(tx, invoice, models.ImportConflictError)
+	return issues, err
+}
+
+// SaveInvoice validates and saves an invoice and its products inside tx, deciding with onConflict what
+// to do when its number is taken: return ErrInvoiceExists (error), leave the existing invoice (skip),
+// or rewrite it (update or replace, see reviseInvoice). It returns the action taken: create, update or skip.
+func SaveInvoice(tx *sql.Tx, invoice *models.Invoice, onConflict string) (string, []models.Issue, error) {
+	lookups, err := LoadInvoiceLookups(tx)
+	if err != nil {
+		return "", nil, err
+	}
+	issues, err := lookups.validate(invoice)
+	if err != nil {
+		return "", nil, err
 	}
 
 	// Check for duplicate invoice
-	exists, err := CheckInvoiceExists(db, invoice.InvoiceNo)
+	exists, err := CheckInvoiceExists(tx, invoice.InvoiceNo)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
+	var existing models.Invoice
 	if exists {
-		return errors.New("duplicate invoice number")
+		switch onConflict {
+		case models.ImportConflictSkip:
+			return models.ImportActionSkip, nil, nil
+		case models.ImportConflictUpdate, models.ImportConflictReplace:
+			if existing, err = lockRevisableInvoice(tx, invoice.InvoiceNo); err != nil {
+				return "", nil, err
+			}
+			if onConflict == models.ImportConflictUpdate {
+				keepInvoiceFields(invoice, existing)
+			}
+		default:
+			return "", nil, ErrInvoiceExists
+		}
+	}
+
+	if err := lookups.complete(invoice); err != nil {
+		return "", nil, err
+	}
+	if exists {
+		if err := reviseInvoice(tx, invoice, existing, onConflict); err != nil {
+			return "", nil, err
+		}
+		return models.ImportActionUpdate, utils.Warnings(issues), nil
+	}
+	if err := InsertInvoices(tx, []*models.Invoice{invoice}); err != nil {
+		return "", nil, err
+	}
+	return models.ImportActionCreate, utils.Warnings(issues), nil
+}
+
+// InvoiceLookups holds the payment methods and tax rates invoices are checked and priced against,
+// so a batch of invoices can be prepared without a query per invoice.
+type InvoiceLookups struct {
+	paymentMethods map[string]bool           // Active payment methods
+	credit         map[string]bool           // Payment methods whose invoices are paid later
+	taxRates       map[string]models.Percent // Rates of the active tax codes
+}
+
+// LoadInvoiceLookups reads the payment methods and the active tax rates.
+func LoadInvoiceLookups(q Querier) (*InvoiceLookups, error) {
+	lookups := &InvoiceLookups{paymentMethods: map[string]bool{}, credit: map[string]bool{}, taxRates: map[string]models.Percent{}}
+	rows, err := q.Query(`SELECT code, active, is_credit FROM payment_methods`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var active, isCredit bool
+		if err := rows.Scan(&code, &active, &isCredit); err != nil {
+			return nil, err
+		}
+		lookups.paymentMethods[code] = active
+		lookups.credit[code] = isCredit
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// Insert the invoice
-	sqlQuery := `INSERT INTO invoices (invoice_no, date, customer_name, salesperson_name, payment_type, notes) 
-	             VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err = db.Exec(sqlQuery, invoice.InvoiceNo, invoice.Date, invoice.CustomerName, invoice.SalespersonName, invoice.PaymentType, invoice.Notes)
+	rows, err = q.Query(`SELECT code, rate FROM tax_rates WHERE active`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var rate models.Percent
+		if err := rows.Scan(&code, &rate); err != nil {
+			return nil, err
+		}
+		lookups.taxRates[code] = rate
+	}
+	return lookups, rows.Err()
+}
+
+// PrepareInvoice validates a new invoice and fills in the values the server computes, as SaveInvoice
+// does, for InsertInvoices to insert it with others. It returns the warnings raised by the validation rules.
+func PrepareInvoice(lookups *InvoiceLookups, invoice *models.Invoice) ([]models.Issue, error) {
+	issues, err := lookups.validate(invoice)
+	if err != nil {
+		return nil, err
+	}
+	if err := lookups.complete(invoice); err != nil {
+		return nil, err
+	}
+	return utils.Warnings(issues), nil
+}
+
+// validate fills in the unit or total values the client left out, then validates the invoice and its
+// products against the shared rule set and the active payment methods.
+func (l *InvoiceLookups) validate(invoice *models.Invoice) ([]models.Issue, error) {
+	for i := range invoice.Products {
+		utils.ResolveProductPricing(&invoice.Products[i])
+	}
+	issues := utils.ValidateInvoice(*invoice, l.paymentMethods)
+	if utils.IssuesError(issues) != nil {
+		return nil, &ValidationError{Issues: issues}
+	}
+	return issues, nil
+}
+
+// complete fills in the defaults, the tax of each product at the current rate of its tax code, the
+// totals and the due date of a validated invoice.
+func (l *InvoiceLookups) complete(invoice *models.Invoice) error {
+	if invoice.TaxMode == "" {
+		invoice.TaxMode = models.TaxModeExclusive
+	}
+	if invoice.Currency == "" {
+		invoice.Currency = utils.BaseCurrency()
+	}
+	if invoice.Status == "" {
+		invoice.Status = models.InvoiceStatusDraft
+	}
+	invoice.IssuedAt = nil
+	if invoice.Status == models.InvoiceStatusIssued {
+		issuedAt := time.Now()
+		invoice.IssuedAt = &issuedAt
+	}
+
+	invoice.TaxTotal = 0
+	for i := range invoice.Products {
+		product := &invoice.Products[i]
+		var rate models.Percent
+		if product.TaxCode != "" {
+			var ok bool
+			if rate, ok = l.taxRates[product.TaxCode]; !ok {
+				return &ValidationError{Issues: []models.Issue{{
+					Field:    fmt.Sprintf("products[%d].tax_code", i),
+					Rule:     "tax_code",
+					Severity: models.SeverityError,
+					Message:  fmt.Sprintf("products[%d]: unknown or inactive tax code: %s", i, product.TaxCode),
+				}}}
+			}
+		}
+		utils.ApplyProductTax(product, rate, invoice.TaxMode)
+		invoice.TaxTotal += product.TaxAmount
+	}
+
+	invoice.TotalAmount = utils.InvoiceTotalAmount(*invoice)
+
+	// Credit invoices fall due after their payment terms; other payment methods are settled immediately
+	setDueDate(invoice, l.credit[invoice.PaymentType])
+	return nil
+}
+
+// invoiceInsertBatch is how many invoices InsertInvoices sends per statement, keeping the
+// 14 parameters of each row under the PostgreSQL limit of 65535 per statement.
+const invoiceInsertBatch = 1000
+
+// InsertInvoices inserts invoices prepared by PrepareInvoice and their products inside tx with multi-row
+// statements, and sets their IDs. It returns ErrInvoiceExists if any of the numbers is taken, without
+// telling which: the caller rolls back and saves the invoices one at a time to find out.
+func InsertInvoices(tx *sql.Tx, invoices []*models.Invoice) error {
+	for start := 0; start < len(invoices); start += invoiceInsertBatch {
+		batch := invoices[start:min(start+invoiceInsertBatch, len(invoices))]
+
+		values := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*14)
+		for i, invoice := range batch {
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13, n+14)
+			args = append(args, invoice.InvoiceNo, invoice.Date, invoice.CustomerName, invoice.SalespersonName, invoice.PaymentType,
+				invoice.Notes, invoice.Currency, invoice.TaxMode, invoice.TaxTotal, invoice.TotalAmount, invoice.DueDate,
+				invoice.PaymentTerms, invoice.Status, invoice.IssuedAt)
+		}
+
+		sqlQuery := `INSERT INTO invoices (invoice_no, date, customer_name, salesperson_name, payment_type, notes, currency, tax_mode, tax_total,
+		                                   total_amount, due_date, payment_terms_days, status, issued_at)
+		             VALUES ` + strings.Join(values, ", ") + `
+		             RETURNING id, invoice_no`
+		rows, err := tx.Query(sqlQuery, args...)
+		if err == nil {
+			err = scanInvoiceIDs(rows, batch)
+		}
+		if isUniqueViolation(err) {
+			// Another transaction took a number after it was checked
+			return ErrInvoiceExists
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var products []*models.Product
+	for _, invoice := range invoices {
+		for i := range invoice.Products {
+			invoice.Products[i].InvoiceNo = invoice.InvoiceNo
+			products = append(products, &invoice.Products[i])
+		}
+	}
+	return insertProductRows(tx, products)
+}
+
+// scanInvoiceIDs sets the IDs returned by an invoice insert on the invoices it inserted, matched by number.
+func scanInvoiceIDs(rows *sql.Rows, invoices []*models.Invoice) error {
+	defer rows.Close()
+	ids := make(map[string]int, len(invoices))
+	for rows.Next() {
+		var id int
+		var invoiceNo string
+		if err := rows.Scan(&id, &invoiceNo); err != nil {
+			return err
+		}
+		ids[invoiceNo] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		invoice.ID = ids[invoice.InvoiceNo]
+	}
+	return nil
+}
+
+// lockRevisableInvoice locks an existing invoice an import is about to rewrite and loads the fields it keeps.
+// Drafts and issued invoices nothing refers to yet can be rewritten, so a corrected file can be imported
+// again over the invoices it created: void invoices, and invoices with payments or credit notes, return
+// ErrInvoiceNotEditable.
+func lockRevisableInvoice(tx *sql.Tx, invoiceNo string) (models.Invoice, error) {
+	var existing models.Invoice
+	var paid, credited bool
+	sqlQuery := `SELECT invoice_no, status, issued_at, COALESCE(notes, ''), currency, tax_mode, payment_terms_days,
+	                    EXISTS (SELECT 1 FROM payments p WHERE p.invoice_no = i.invoice_no),
+	                    EXISTS (SELECT 1 FROM credit_notes c WHERE c.invoice_no = i.invoice_no)
+	             FROM invoices i
+	             WHERE invoice_no = $1
+	             FOR UPDATE`
+	err := tx.QueryRow(sqlQuery, invoiceNo).Scan(&existing.InvoiceNo, &existing.Status, &existing.IssuedAt, &existing.Notes,
+		&existing.Currency, &existing.TaxMode, &existing.PaymentTerms, &paid, &credited)
+	switch {
+	case err != nil:
+		return existing, err
+	case existing.Status == models.InvoiceStatusVoid:
+		return existing, fmt.Errorf("%w: void invoices can't be changed", ErrInvoiceNotEditable)
+	case paid:
+		return existing, fmt.Errorf("%w: invoices with payments can't be changed", ErrInvoiceNotEditable)
+	case credited:
+		return existing, fmt.Errorf("%w: invoices with credit notes can't be changed", ErrInvoiceNotEditable)
+	}
+	return existing, nil
+}
+
+// keepInvoiceFields fills in the optional fields an update leaves empty from the existing invoice,
+// so only the values given change. A due date left out keeps the payment terms.
+func keepInvoiceFields(invoice *models.Invoice, existing models.Invoice) {
+	if invoice.Notes == "" {
+		invoice.Notes = existing.Notes
+	}
+	if invoice.Currency == "" {
+		invoice.Currency = existing.Currency
+	}
+	if invoice.TaxMode == "" {
+		invoice.TaxMode = existing.TaxMode
+	}
+	if invoice.DueDate == nil && invoice.PaymentTerms == nil {
+		invoice.PaymentTerms = existing.PaymentTerms
+	}
+}
+
+// reviseInvoice rewrites an existing invoice locked by lockRevisableInvoice with a prepared invoice.
+// Its status and issue date are kept. An update reconciles the product lines, updating the lines whose item name
+// matches and keeping their IDs, adding the new lines and deleting the lines left out; a replace
+// deletes the old lines and inserts the new ones.
+func reviseInvoice(tx *sql.Tx, invoice *models.Invoice, existing models.Invoice, onConflict string) error {
+	invoice.Status, invoice.IssuedAt = existing.Status, existing.IssuedAt
+	sqlQuery := `UPDATE invoices
+	             SET date = $1, customer_name = $2, salesperson_name = $3, payment_type = $4, notes = NULLIF($5, ''), currency = $6,
+	                 tax_mode = $7, tax_total = $8, total_amount = $9, due_date = $10, payment_terms_days = $11
+	             WHERE invoice_no = $12
+	             RETURNING id`
+	err := tx.QueryRow(sqlQuery, invoice.Date, invoice.CustomerName, invoice.SalespersonName, invoice.PaymentType, invoice.Notes,
+		invoice.Currency, invoice.TaxMode, invoice.TaxTotal, invoice.TotalAmount, invoice.DueDate, invoice.PaymentTerms, invoice.InvoiceNo).
+		Scan(&invoice.ID)
 	if err != nil {
 		return err
 	}
 
-	// Validate the Products Fields before proceeding.
-	for _, product := range invoice.Products {
-		if err = utils.ValidateProduct(product); err != nil {
+	if onConflict == models.ImportConflictReplace {
+		if _, err := tx.Exec(`DELETE FROM products WHERE invoice_no = $1`, invoice.InvoiceNo); err != nil {
 			return err
 		}
+		return insertProducts(tx, invoice.InvoiceNo, invoice.Products)
 	}
 
-	// Insert associated products
-	productQuery := `INSERT INTO products (invoice_no, item_name, quantity, total_cost, total_price)
-	                 VALUES ($1, $2, $3, $4, $5)`
-	for _, product := range invoice.Products {
-		_, err = tx.Exec(productQuery, invoice.InvoiceNo, product.ItemName, product.Quantity, product.TotalCost, product.TotalPrice)
+	lines, err := getInvoiceProducts(tx, invoice.InvoiceNo)
+	if err != nil {
+		return err
+	}
+	var added []int
+	for i := range invoice.Products {
+		product := &invoice.Products[i]
+		match := -1
+		for j := range lines {
+			if lines[j].ID != 0 && lines[j].ItemName == product.ItemName {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			added = append(added, i)
+			continue
+		}
+		product.ID = lines[match].ID
+		lines[match].ID = 0 // Matched lines are kept
+		if err := updateProduct(tx, invoice.InvoiceNo, product); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		if line.ID != 0 {
+			if _, err := tx.Exec(`DELETE FROM products WHERE id = $1`, line.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, i := range added {
+		if err := insertProduct(tx, invoice.InvoiceNo, &invoice.Products[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareProduct fills in the unit or total values the client left out, validates the product
+// and computes its tax from the current rate of its tax code.
+func prepareProduct(q Querier, product *models.Product, taxMode string) error {
+	utils.ResolveProductPricing(product)
+	if err := utils.ValidateProduct(*product); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	return applyTaxRate(q, product, taxMode)
+}
+
+// applyTaxRate computes the tax of a product from the current rate of its tax code.
+func applyTaxRate(q Querier, product *models.Product, taxMode string) error {
+	var rate models.Percent
+	if product.TaxCode != "" {
+		taxRate, err := GetTaxRate(q, product.TaxCode)
+		if err == sql.ErrNoRows || (err == nil && !taxRate.Active) {
+			return fmt.Errorf("%w: unknown or inactive tax code: %s", ErrInvalidInvoice, product.TaxCode)
+		}
 		if err != nil {
 			return err
 		}
+		rate = taxRate.Rate
 	}
+	utils.ApplyProductTax(product, rate, taxMode)
+	return nil
+}
 
-	return tx.Commit()
+// insertProduct inserts a prepared product into an invoice and sets its ID.
+func insertProduct(q Querier, invoiceNo string, product *models.Product) error {
+	productQuery := `INSERT INTO products (invoice_no, item_name, quantity, unit_cost, unit_price, discount_amount, discount_percent, total_cost, total_price,
+	                                       tax_code, tax_rate, tax_amount)
+	                 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
+	                 RETURNING id`
+	product.InvoiceNo = invoiceNo
+	return q.QueryRow(productQuery, invoiceNo, product.ItemName, product.Quantity, product.UnitCost, product.UnitPrice,
+		product.DiscountAmount, product.DiscountPercent, product.TotalCost, product.TotalPrice,
+		product.TaxCode, product.TaxRate, product.TaxAmount).Scan(&product.ID)
 }
 
+// productInsertBatch is how many products insertProductRows sends per statement, keeping the
+// 12 parameters of each row under the PostgreSQL limit of 65535 per statement.
+const productInsertBatch = 1000
+
+// insertProducts inserts the prepared products of an invoice with multi-row statements and sets their IDs.
+func insertProducts(q Querier, invoiceNo string, products []models.Product) error {
+	rows := make([]*models.Product, len(products))
+	for i := range products {
+		products[i].InvoiceNo = invoiceNo
+		rows[i] = &products[i]
+	}
+	return insertProductRows(q, rows)
+}
+
+// insertProductRows inserts prepared products, each into the invoice its InvoiceNo names, with
+// multi-row statements and sets their IDs.
+func insertProductRows(q Querier, products []*models.Product) error {
+	for start := 0; start < len(products); start += productInsertBatch {
+		batch := products[start:min(start+productInsertBatch, len(products))]
+
+		values := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*12)
+		for i, product := range batch {
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12)
+			args = append(args, product.InvoiceNo, product.ItemName, product.Quantity, product.UnitCost, product.UnitPrice,
+				product.DiscountAmount, product.DiscountPercent, product.TotalCost, product.TotalPrice,
+				product.TaxCode, product.TaxRate, product.TaxAmount)
+		}
+
+		productQuery := `INSERT INTO products (invoice_no, item_name, quantity, unit_cost, unit_price, discount_amount, discount_percent, total_cost, total_price,
+		                                       tax_code, tax_rate, tax_amount)
+		                 VALUES ` + strings.Join(values, ", ") + `
+		                 RETURNING id`
+		rows, err := q.Query(productQuery, args...)
+		if err != nil {
+			return err
+		}
+		if err := scanProductIDs(rows, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanProductIDs sets the IDs returned by a product insert, which come back in the order of its VALUES rows.
+func scanProductIDs(rows *sql.Rows, products []*models.Product) error {
+	defer rows.Close()
+	for i := 0; rows.Next() && i < len(products); i++ {
+		if err := rows.Scan(&products[i].ID); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// updateProduct replaces a prepared product of an invoice, found by its ID.
+// It returns sql.ErrNoRows if the invoice has no such product.
+func updateProduct(q Querier, invoiceNo string, product *models.Product) error {
+	sqlQuery := `UPDATE products
+	             SET item_name = $1, quantity = $2, unit_cost = $3, unit_price = $4, discount_amount = $5, discount_percent = $6,
+	                 total_cost = $7, total_price = $8, tax_code = NULLIF($9, ''), tax_rate = $10, tax_amount = $11
+	             WHERE invoice_no = $12 AND id = $13`
+	result, err := q.Exec(sqlQuery, product.ItemName, product.Quantity, product.UnitCost, product.UnitPrice, product.DiscountAmount,
+		product.DiscountPercent, product.TotalCost, product.TotalPrice, product.TaxCode, product.TaxRate, product.TaxAmount,
+		invoiceNo, product.ID)
+	if err != nil {
+		return err
+	}
+	product.InvoiceNo = invoiceNo
+	return requireAffected(result)
+}
+
+// setDueDate fills in the due date or payment terms of a credit invoice from the other,
+// defaulting to DefaultPaymentTermsDays, and clears both for other payment methods.
+func setDueDate(invoice *models.Invoice, isCredit bool) {
+	if !isCredit {
+		invoice.DueDate = nil
+		invoice.PaymentTerms = nil
+		return
+	}
+	if invoice.DueDate != nil {
+		terms := utils.DaysBetween(invoice.Date, *invoice.DueDate)
+		invoice.PaymentTerms = &terms
+		return
+	}
+	terms := utils.DefaultPaymentTermsDays()
+	if invoice.PaymentTerms != nil {
+		terms = *invoice.PaymentTerms
+	}
+	dueDate := invoice.Date.AddDate(0, 0, terms)
+	invoice.DueDate = &dueDate
+	invoice.PaymentTerms = &terms
+}
+
+// invoiceColumns is the column list scanned by scanInvoices, including the amounts paid and credited and whether the payment method is credit.
+const invoiceColumns = `id, invoice_no, date, customer_name, salesperson_name, payment_type, COALESCE(notes, ''), currency, tax_mode, tax_total,
+	total_amount, due_date, payment_terms_days, status, issued_at, voided_at, COALESCE(void_reason, ''),
+	(SELECT COALESCE(SUM(amount), 0) FROM payments WHERE payments.invoice_no = invoices.invoice_no),
+	(SELECT COALESCE(SUM(total_amount), 0) FROM credit_notes WHERE credit_notes.invoice_no = invoices.invoice_no),
+	(SELECT is_credit FROM payment_methods WHERE payment_methods.code = invoices.payment_type)`
+
 // GetInvoices retrieves a list of invoices based on the provided parameters (date, size, page)
-// It also calculates and returns the total profit and total cash transactions for the given date
-func GetInvoices(db *sql.DB, payload models.InvoiceRequest) (invoices []models.Invoice, totalProfit, totalCash float64, err error) {
-	sqlQuery := `SELECT invoice_no, date, customer_name, salesperson_name, payment_type, notes 
+// It also calculates and returns the total profit, total cash transactions and tax summary for the given date
+func GetInvoices(db *sql.DB, payload models.InvoiceRequest) (invoices []models.Invoice, totals models.InvoiceTotals, err error) {
+	sqlQuery := `SELECT ` + invoiceColumns + ` 
 	             FROM invoices 
 	             WHERE date = $1 
 	             LIMIT $2 OFFSET $3`
 
-	rows, err := db.Query(sqlQuery, payload.Date, payload.Size, (payload.Page-1)*(payload.Size))
+	invoices, err = scanInvoices(db, sqlQuery, payload.Date, payload.Size, (payload.Page-1)*(payload.Size))
+	if err != nil {
+		return nil, totals, err
+	}
+
+	// Credit notes against the listed invoices reduce their revenue and profit
+	invoiceNos := make([]string, len(invoices))
+	for i, invoice := range invoices {
+		invoiceNos[i] = invoice.InvoiceNo
+	}
+	creditNotes, err := GetCreditNotesForInvoices(db, invoiceNos)
+	if err != nil {
+		return nil, totals, err
+	}
+
+	// Calculate total profit, total cash transactions and the tax summary
+	totals, err = SummarizeInvoices(db, invoices, creditNotes, utils.BaseCurrency())
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, totals, err
+	}
+	return invoices, totals, nil
+}
+
+// GetInvoicesByDateRange retrieves the issued invoices dated between from and to, inclusive, with their products.
+// Drafts and void invoices are left out.
+func GetInvoicesByDateRange(db *sql.DB, from, to time.Time) ([]models.Invoice, error) {
+	sqlQuery := `SELECT ` + invoiceColumns + ` 
+	             FROM invoices 
+	             WHERE date BETWEEN $1 AND $2 AND status = 'issued' 
+	             ORDER BY date, invoice_no`
+	return scanInvoices(db, sqlQuery, from, to)
+}
+
+// GetInvoice retrieves an invoice by its number with its products, or sql.ErrNoRows if there is none.
+func GetInvoice(db *sql.DB, invoiceNo string) (models.Invoice, error) {
+	sqlQuery := `SELECT ` + invoiceColumns + ` 
+	             FROM invoices 
+	             WHERE invoice_no = $1`
+	invoices, err := scanInvoices(db, sqlQuery, invoiceNo)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	if len(invoices) == 0 {
+		return models.Invoice{}, sql.ErrNoRows
+	}
+	return invoices[0], nil
+}
+
+// scanInvoices runs an invoice query selecting invoiceColumns and loads each invoice's products.
+func scanInvoices(db *sql.DB, sqlQuery string, args ...interface{}) ([]models.Invoice, error) {
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
-	// Retrieve invoices
+	var invoices []models.Invoice
+	today := time.Now()
 	for rows.Next() {
 		var invoice models.Invoice
-		if err := rows.Scan(&invoice.InvoiceNo, &invoice.Date, &invoice.CustomerName, &invoice.SalespersonName, &invoice.PaymentType, &invoice.Notes); err != nil {
-			return nil, 0, 0, err
+		if err := rows.Scan(&invoice.ID, &invoice.InvoiceNo, &invoice.Date, &invoice.CustomerName, &invoice.SalespersonName, &invoice.PaymentType, &invoice.Notes,
+			&invoice.Currency, &invoice.TaxMode, &invoice.TaxTotal,
+			&invoice.TotalAmount, &invoice.DueDate, &invoice.PaymentTerms, &invoice.Status, &invoice.IssuedAt, &invoice.VoidedAt, &invoice.VoidReason,
+			&invoice.PaidAmount, &invoice.CreditedAmount, &invoice.IsCredit); err != nil {
+			return nil, err
 		}
+		invoice.PaymentStatus = utils.PaymentStatus(invoice.TotalAmount-invoice.CreditedAmount, invoice.PaidAmount, invoice.DueDate, invoice.IsCredit, today)
+		invoices = append(invoices, invoice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Retrieve products for the current invoice
-		productsQuery := `SELECT id, invoice_no, item_name, quantity, total_cost, total_price 
-		                  FROM products 
-		                  WHERE invoice_no = $1`
-		productRows, err := db.Query(productsQuery, invoice.InvoiceNo)
+	// Retrieve products for each invoice
+	for i := range invoices {
+		invoices[i].Products, err = getInvoiceProducts(db, invoices[i].InvoiceNo)
 		if err != nil {
-			return nil, 0, 0, err
+			return nil, err
 		}
+	}
+	return invoices, nil
+}
 
-		var products []models.Product
-		for productRows.Next() {
-			var product models.Product
-			if err := productRows.Scan(&product.ID, &product.InvoiceNo, &product.ItemName, &product.Quantity, &product.TotalCost, &product.TotalPrice); err != nil {
-				return nil, 0, 0, err
-			}
-			products = append(products, product)
-		}
-		productRows.Close()
+// getInvoiceProducts retrieves the products of an invoice.
+func getInvoiceProducts(q Querier, invoiceNo string) ([]models.Product, error) {
+	productsQuery := `SELECT id, invoice_no, item_name, quantity, unit_cost, unit_price, discount_amount, discount_percent, total_cost, total_price,
+	                         COALESCE(tax_code, ''), tax_rate, tax_amount 
+	                  FROM products 
+	                  WHERE invoice_no = $1
+	                  ORDER BY id`
+	productRows, err := q.Query(productsQuery, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	defer productRows.Close()
 
-		invoice.Products = products
-		invoices = append(invoices, invoice)
+	var products []models.Product
+	for productRows.Next() {
+		var product models.Product
+		if err := productRows.Scan(&product.ID, &product.InvoiceNo, &product.ItemName, &product.Quantity, &product.UnitCost, &product.UnitPrice,
+			&product.DiscountAmount, &product.DiscountPercent, &product.TotalCost, &product.TotalPrice,
+			&product.TaxCode, &product.TaxRate, &product.TaxAmount); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
 	}
+	return products, productRows.Err()
+}
+
+// SummarizeInvoices totals the issued invoices in baseCurrency, converting each line at the rate on its invoice date,
+// and keeps unconverted subtotals per invoice currency. Drafts and void invoices are skipped.
+// Credit notes count as negative revenue and profit, converted at the rate on the credit note date.
+func SummarizeInvoices(q Querier, invoices []models.Invoice, creditNotes []models.CreditNote, baseCurrency string) (models.InvoiceTotals, error) {
+	totals := models.InvoiceTotals{BaseCurrency: baseCurrency, ByCurrency: []models.CurrencySubtotal{}}
+	converter := NewConverter(q, baseCurrency)
 
-	// Calculate total profit and total cash transactions
 	for _, inv := range invoices {
-		for _, product := range inv.Products {
-			totalProfit += product.TotalPrice - product.TotalCost
-			if inv.PaymentType == "CASH" {
-				totalCash += product.TotalPrice
-			}
+		if inv.Status != models.InvoiceStatusIssued {
+			continue
+		}
+		rate, err := converter.Rate(inv.Currency, inv.Date)
+		if err != nil {
+			return totals, err
 		}
+		subtotal := currencySubtotal(&totals, inv.Currency)
+		subtotal.InvoiceCount++
+		totals.InvoiceCount++
+		addLines(&totals, subtotal, inv.Products, rate, inv.TaxMode, inv.IsCredit)
 	}
 
-	return invoices, totalProfit, totalCash, nil
+	for _, creditNote := range creditNotes {
+		rate, err := converter.Rate(creditNote.Currency, creditNote.Date)
+		if err != nil {
+			return totals, err
+		}
+		subtotal := currencySubtotal(&totals, creditNote.Currency)
+		subtotal.CreditNoteCount++
+		totals.CreditNoteCount++
+
+		reversals := make([]models.Product, len(creditNote.Lines))
+		for i, line := range creditNote.Lines {
+			reversals[i] = line.Reversal()
+		}
+		addLines(&totals, subtotal, reversals, rate, creditNote.TaxMode, creditNote.IsCredit)
+	}
+	return totals, nil
 }
 
-func UpdateInvoice(db *sql.DB, invoice models.UpdateInvoiceRequest) error {
+// addLines adds product lines in one currency to its subtotal and, converted at rate, to the totals.
+// Lines paid with a non-credit payment method also count towards the cash total.
+func addLines(totals *models.InvoiceTotals, subtotal *models.CurrencySubtotal, products []models.Product, rate models.Rate, taxMode string, isCredit bool) {
+	for _, product := range products {
+		taxable := utils.TaxableAmount(product, taxMode)
+		subtotal.Revenue += taxable
+		subtotal.Cost += product.TotalCost
+		subtotal.Profit += taxable - product.TotalCost
+		subtotal.Tax += product.TaxAmount
+
+		// Convert the line into the base currency
+		base := product
+		base.TotalCost = product.TotalCost.Convert(rate)
+		base.TaxAmount = product.TaxAmount.Convert(rate)
+		baseTaxable := taxable.Convert(rate)
+
+		subtotal.BaseRevenue += baseTaxable
+		subtotal.BaseProfit += baseTaxable - base.TotalCost
+		subtotal.BaseTax += base.TaxAmount
+		totals.Revenue += baseTaxable
+		totals.Cost += base.TotalCost
+		totals.TotalProfit += baseTaxable - base.TotalCost
+		if !isCredit {
+			totals.TotalCash += baseTaxable + base.TaxAmount
+		}
+		totals.Tax.AddLine(base, baseTaxable)
+	}
+}
+
+// currencySubtotal returns the subtotal for currency, adding it if it isn't there yet.
+func currencySubtotal(totals *models.InvoiceTotals, currency string) *models.CurrencySubtotal {
+	for i := range totals.ByCurrency {
+		if totals.ByCurrency[i].Currency == currency {
+			return &totals.ByCurrency[i]
+		}
+	}
+	totals.ByCurrency = append(totals.ByCurrency, models.CurrencySubtotal{Currency: currency})
+	return &totals.ByCurrency[len(totals.ByCurrency)-1]
+}
+
+// UpdateInvoice updates the provided fields of an invoice. Drafts can be changed freely,
+// issued invoices only have their notes changed and void invoices can't be changed.
+// It returns the warnings raised by the validation rules.
+func UpdateInvoice(db *sql.DB, invoice models.UpdateInvoiceRequest) ([]models.Issue, error) {
 	// Validate if at least one field is provided for the update
-	if invoice.Date.IsZero() && invoice.CustomerName == "" && invoice.SalespersonName == "" && invoice.PaymentType == "" && invoice.Notes == "" {
-		return errors.New("no fields to update")
+	if invoice.Date.IsZero() && invoice.CustomerName == "" && invoice.SalespersonName == "" && invoice.PaymentType == "" && invoice.Notes == "" &&
+		invoice.DueDate.IsZero() {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidInvoice)
+	}
+	if issues := utils.ValidateInvoiceUpdateFields(invoice); len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockInvoice(tx, invoice.InvoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	onlyNotes := invoice.Date.IsZero() && invoice.CustomerName == "" && invoice.SalespersonName == "" && invoice.PaymentType == "" &&
+		invoice.DueDate.IsZero()
+	if status == models.InvoiceStatusVoid {
+		return nil, fmt.Errorf("%w: void invoices can't be changed", ErrInvoiceNotEditable)
+	}
+	if status == models.InvoiceStatusIssued && !onlyNotes {
+		return nil, fmt.Errorf("%w: issued invoices can only have their notes changed", ErrInvoiceNotEditable)
+	}
+
+	issues := utils.ValidateInvoiceUpdate(invoice)
+	if err := utils.IssuesError(issues); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
 	}
 
 	// Dynamic query
@@ -144,6 +814,13 @@ func UpdateInvoice(db *sql.DB, invoice models.UpdateInvoiceRequest) error {
 		argCount++
 	}
 	if invoice.PaymentType != "" {
+		paymentMethods, err := GetActivePaymentMethodCodes(tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := utils.ValidateInvoicePaymentType(invoice.PaymentType, paymentMethods); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+		}
 		query += fmt.Sprintf(" payment_type = $%d,", argCount)
 		args = append(args, invoice.PaymentType)
 		argCount++
@@ -153,30 +830,179 @@ func UpdateInvoice(db *sql.DB, invoice models.UpdateInvoiceRequest) error {
 		args = append(args, invoice.Notes)
 		argCount++
 	}
+	if !invoice.Date.IsZero() || invoice.PaymentType != "" || !invoice.DueDate.IsZero() {
+		// Work the due date out again with the rule invoices are created with
+		terms, err := updatedTerms(tx, invoice)
+		if err != nil {
+			return nil, err
+		}
+		query += fmt.Sprintf(" due_date = $%d, payment_terms_days = $%d,", argCount, argCount+1)
+		args = append(args, terms.DueDate, terms.PaymentTerms)
+		argCount += 2
+	}
 
 	// Remove trailing comma and add WHERE clause
 	query = strings.TrimSuffix(query, ",")
 	query += fmt.Sprintf(" WHERE invoice_no = $%d", argCount)
 	args = append(args, invoice.InvoiceNo)
 
-	_, err := db.Exec(query, args...)
-	return err
+	if _, err := tx.Exec(query, args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return utils.Warnings(issues), nil
+}
+
+// updatedTerms applies the date, payment method and due date of an update to the stored invoice and sets
+// its due date and payment terms with setDueDate, as on create. A new date or credit payment method without
+// a due date keeps the payment terms, so the due date moves with the invoice date; switching to a credit
+// method that had no terms gives the default terms. A due date on a non-credit payment method, or before
+// the invoice date, is rejected with ErrInvalidInvoice.
+func updatedTerms(tx *sql.Tx, update models.UpdateInvoiceRequest) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.QueryRow(`SELECT date, payment_type, payment_terms_days FROM invoices WHERE invoice_no = $1`, update.InvoiceNo).
+		Scan(&invoice.Date, &invoice.PaymentType, &invoice.PaymentTerms)
+	if err != nil {
+		return invoice, err
+	}
+	if !update.Date.IsZero() {
+		invoice.Date = update.Date
+	}
+	if update.PaymentType != "" {
+		invoice.PaymentType = update.PaymentType
+	}
+	if !update.DueDate.IsZero() {
+		dueDate := update.DueDate
+		invoice.DueDate = &dueDate
+	}
+
+	isCredit, err := IsCreditPaymentMethod(tx, invoice.PaymentType)
+	if err != nil {
+		return invoice, err
+	}
+	if invoice.DueDate != nil && !isCredit {
+		return invoice, fmt.Errorf("%w: due_date is only allowed for a credit payment method", ErrInvalidInvoice)
+	}
+	if invoice.DueDate != nil && utils.DaysBetween(invoice.Date, *invoice.DueDate) < 0 {
+		return invoice, fmt.Errorf("%w: due_date cannot be before date", ErrInvalidInvoice)
+	}
+	setDueDate(&invoice, isCredit)
+	return invoice, nil
 }
 
-// DeleteInvoice removes an invoice from the database
+// DeleteInvoice removes a draft invoice from the database. Issued invoices must be voided instead.
 func DeleteInvoice(db *sql.DB, invoiceNo string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockInvoice(tx, invoiceNo)
+	if err != nil {
+		return err
+	}
+	if status != models.InvoiceStatusDraft {
+		return fmt.Errorf("%w: only drafts can be deleted, void %s invoices instead", ErrInvoiceNotEditable, status)
+	}
+
 	sqlQuery := `DELETE FROM invoices WHERE invoice_no = $1`
-	_, err = db.Exec(sqlQuery, invoiceNo)
-	return err
+	if _, err = tx.Exec(sqlQuery, invoiceNo); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IssueInvoice moves a draft invoice to issued, after which it can only have its notes changed.
+func IssueInvoice(db *sql.DB, invoiceNo string) error {
+	return transitionInvoice(db, invoiceNo, models.InvoiceStatusIssued, "")
+}
+
+// VoidInvoice moves an invoice to void, recording why. Void invoices are left out of totals and reports.
+func VoidInvoice(db *sql.DB, invoiceNo, reason string) error {
+	if utf8.RuneCountInString(reason) < 5 {
+		return fmt.Errorf("%w: void reason must have at least 5 characters", ErrInvalidStatusTransition)
+	}
+	return transitionInvoice(db, invoiceNo, models.InvoiceStatusVoid, reason)
+}
+
+// transitionInvoice moves an invoice to a new status if the workflow allows it.
+func transitionInvoice(db *sql.DB, invoiceNo, status, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, _, err := lockInvoice(tx, invoiceNo)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateStatusTransition(current, status); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStatusTransition, err)
+	}
+
+	switch status {
+	case models.InvoiceStatusIssued:
+		var productCount int
+		if err := tx.QueryRow(`SELECT COUNT(1) FROM products WHERE invoice_no = $1`, invoiceNo).Scan(&productCount); err != nil {
+			return err
+		}
+		if productCount == 0 {
+			return fmt.Errorf("%w: an invoice needs at least one product to be issued", ErrInvalidStatusTransition)
+		}
+		_, err = tx.Exec(`UPDATE invoices SET status = $1, issued_at = NOW() WHERE invoice_no = $2`, status, invoiceNo)
+	case models.InvoiceStatusVoid:
+		var creditNoteCount int
+		if err := tx.QueryRow(`SELECT COUNT(1) FROM credit_notes WHERE invoice_no = $1`, invoiceNo).Scan(&creditNoteCount); err != nil {
+			return err
+		}
+		if creditNoteCount > 0 {
+			return fmt.Errorf("%w: an invoice with credit notes can't be voided", ErrInvalidStatusTransition)
+		}
+		_, err = tx.Exec(`UPDATE invoices SET status = $1, voided_at = NOW(), void_reason = $2 WHERE invoice_no = $3`, status, reason, invoiceNo)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockInvoice locks an invoice row until the transaction ends and returns its status and tax mode.
+// It returns sql.ErrNoRows if the invoice doesn't exist.
+func lockInvoice(tx *sql.Tx, invoiceNo string) (status, taxMode string, err error) {
+	err = tx.QueryRow(`SELECT status, tax_mode FROM invoices WHERE invoice_no = $1 FOR UPDATE`, invoiceNo).Scan(&status, &taxMode)
+	return status, taxMode, err
 }
 
 // CheckInvoiceExists checks if an invoice with the given invoice number already exists in the database.
-func CheckInvoiceExists(db *sql.DB, invoiceNo string) (bool, error) {
+func CheckInvoiceExists(q Querier, invoiceNo string) (bool, error) {
 	sqlQuery := `SELECT COUNT(1) FROM invoices WHERE invoice_no = $1`
 	var count int
-	err := db.QueryRow(sqlQuery, invoiceNo).Scan(&count)
+	err := q.QueryRow(sqlQuery, invoiceNo).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+// GetExistingInvoiceNumbers returns which of the given invoice numbers are taken.
+func GetExistingInvoiceNumbers(q Querier, invoiceNos []string) (map[string]bool, error) {
+	rows, err := q.Query(`SELECT invoice_no FROM invoices WHERE invoice_no = ANY($1)`, pq.Array(invoiceNos))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var invoiceNo string
+		if err := rows.Scan(&invoiceNo); err != nil {
+			return nil, err
+		}
+		existing[invoiceNo] = true
+	}
+	return existing, rows.Err()
+}
@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"widatech-technical-challenge/database"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL and migrates it,
+// skipping the test when it isn't set.
+func testDB(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DBMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestSummarizeInvoicesMatchesSQL checks the totals computed in Go against the database's own sums
+// of the same lines, with amounts whose cents add up differently if rounded per line.
+func TestSummarizeInvoicesMatchesSQL(t *testing.T) {
+	db := testDB(t)
+
+	// A date of its own keeps the seeded invoices apart from any others
+	date := time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(time.Now().UnixNano()%10000))
+	prefix := fmt.Sprintf("TEST-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+
+	lines := [][2]models.Money{ // total_cost, total_price
+		{33333, 50001}, {1, 3}, {99999, 100005}, {12345, 12399}, {0, 7}, {250050, 333333},
+	}
+	for i := 0; i < 3; i++ {
+		invoice := models.Invoice{
+			InvoiceNo:       fmt.Sprintf("%s%d", prefix, i),
+			Date:            date,
+			CustomerName:    "Customer",
+			SalespersonName: "Salesperson",
+			PaymentType:     "CASH",
+			Currency:        utils.BaseCurrency(),
+			Status:          models.InvoiceStatusIssued,
+		}
+		for j, line := range lines[i*2 : i*2+2] {
+			invoice.Products = append(invoice.Products, models.Product{
+				ItemName:   fmt.Sprintf("Product %d", j),
+				Quantity:   j + 1,
+				TotalCost:  line[0],
+				TotalPrice: line[1],
+			})
+		}
+		if _, err := CreateInvoice(db, &invoice); err != nil {
+			t.Fatalf("CreateInvoice(%s): %v", invoice.InvoiceNo, err)
+		}
+	}
+
+	invoices, err := GetInvoicesByDateRange(db, date, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals, err := SummarizeInvoices(db, invoices, nil, utils.BaseCurrency())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var revenue, profit models.Money
+	err = db.QueryRow(`SELECT SUM(p.total_price), SUM(p.total_price - p.total_cost)
+	                   FROM products p JOIN invoices i ON i.invoice_no = p.invoice_no
+	                   WHERE i.date = $1 AND i.status = 'issued'`, date).Scan(&revenue, &profit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if totals.InvoiceCount != 3 {
+		t.Errorf("InvoiceCount = %d, want 3", totals.InvoiceCount)
+	}
+	if totals.Revenue != revenue {
+		t.Errorf("Revenue = %s, SQL sum = %s", totals.Revenue, revenue)
+	}
+	if totals.TotalProfit != profit {
+		t.Errorf("TotalProfit = %s, SQL sum = %s", totals.TotalProfit, profit)
+	}
+	if totals.TotalCash != revenue {
+		t.Errorf("TotalCash = %s, want the revenue of the untaxed cash invoices, %s", totals.TotalCash, revenue)
+	}
+}
+
+// TestUpdateInvoiceDueDate checks that updates work the due date out with the rule invoices are created with.
+func TestUpdateInvoiceDueDate(t *testing.T) {
+	db := testDB(t)
+	invoiceNo := fmt.Sprintf("TEST-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no = $1`, invoiceNo)
+	})
+
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	terms := 14
+	invoice := models.Invoice{
+		InvoiceNo:       invoiceNo,
+		Date:            date,
+		CustomerName:    "Customer",
+		SalespersonName: "Salesperson",
+		PaymentType:     "CREDIT",
+		PaymentTerms:    &terms,
+		Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+	}
+	if _, err := CreateInvoice(db, &invoice); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := GetInvoice(db, invoiceNo); err != nil || stored.ID != invoice.ID || len(stored.Products) != 1 {
+		t.Fatalf("GetInvoice = %+v, %v, want id %d with its product", stored, err, invoice.ID)
+	}
+
+	dueDate := func() (*time.Time, *int) {
+		t.Helper()
+		var dueDate *time.Time
+		var terms *int
+		if err := db.QueryRow(`SELECT due_date, payment_terms_days FROM invoices WHERE invoice_no = $1`, invoiceNo).
+			Scan(&dueDate, &terms); err != nil {
+			t.Fatal(err)
+		}
+		return dueDate, terms
+	}
+
+	// A new date keeps the terms and moves the due date
+	if _, err := UpdateInvoice(db, models.UpdateInvoiceRequest{InvoiceNo: invoiceNo, Date: date.AddDate(0, 0, 10)}); err != nil {
+		t.Fatal(err)
+	}
+	if due, terms := dueDate(); due == nil || !due.Equal(date.AddDate(0, 0, 24)) || terms == nil || *terms != 14 {
+		t.Errorf("after a new date, due_date = %v, payment_terms_days = %v, want 2025-01-25 and 14", due, terms)
+	}
+
+	// A non-credit payment method clears the due date and can't be given one
+	if _, err := UpdateInvoice(db, models.UpdateInvoiceRequest{InvoiceNo: invoiceNo, PaymentType: "CASH"}); err != nil {
+		t.Fatal(err)
+	}
+	if due, terms := dueDate(); due != nil || terms != nil {
+		t.Errorf("after switching to CASH, due_date = %v, payment_terms_days = %v, want both cleared", due, terms)
+	}
+	_, err := UpdateInvoice(db, models.UpdateInvoiceRequest{InvoiceNo: invoiceNo, DueDate: date.AddDate(0, 1, 0)})
+	if !errors.Is(err, ErrInvalidInvoice) {
+		t.Errorf("due_date on a CASH invoice: err = %v, want ErrInvalidInvoice", err)
+	}
+}
+
+// TestSaveInvoiceRevisesUnsettledInvoices checks that an import may update drafts and issued invoices,
+// keeping their status and issue date, but rejects the rows of invoices with payments and of void ones.
+func TestSaveInvoiceRevisesUnsettledInvoices(t *testing.T) {
+	db := testDB(t)
+	prefix := fmt.Sprintf("TEST-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+
+	newInvoice := func(invoiceNo, customer string) models.Invoice {
+		return models.Invoice{
+			InvoiceNo:       invoiceNo,
+			Date:            time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			CustomerName:    customer,
+			SalespersonName: "Salesperson",
+			PaymentType:     "CREDIT",
+			Status:          models.InvoiceStatusIssued, // As the import creates invoices
+			Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+		}
+	}
+	issuedAt := map[string]*time.Time{}
+	for _, name := range []string{"draft", "issued", "paid", "void"} {
+		invoice := newInvoice(prefix+name, "Customer")
+		if name == "draft" {
+			invoice.Status = models.InvoiceStatusDraft
+		}
+		if _, err := CreateInvoice(db, &invoice); err != nil {
+			t.Fatal(err)
+		}
+		// Read back as stored, to the microsecond
+		var stored *time.Time
+		if err := db.QueryRow(`SELECT issued_at FROM invoices WHERE invoice_no = $1`, invoice.InvoiceNo).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		issuedAt[name] = stored
+	}
+	payment := models.Payment{InvoiceNo: prefix + "paid", Amount: 50, PaidAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}
+	if _, err := CreatePayment(db, &payment); err != nil {
+		t.Fatal(err)
+	}
+	if err := VoidInvoice(db, prefix+"void", "Sent to the wrong customer"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, onConflict := range []string{models.ImportConflictUpdate, models.ImportConflictReplace} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, status := range map[string]string{"draft": models.InvoiceStatusDraft, "issued": models.InvoiceStatusIssued} {
+			invoice := newInvoice(prefix+name, "New customer")
+			if action, _, err := SaveInvoice(tx, &invoice, onConflict); err != nil || action != models.ImportActionUpdate {
+				t.Errorf("%s of the %s invoice = %q, %v, want update", onConflict, name, action, err)
+				continue
+			}
+			if invoice.Status != status || (issuedAt[name] == nil) != (invoice.IssuedAt == nil) ||
+				(issuedAt[name] != nil && !issuedAt[name].Equal(*invoice.IssuedAt)) {
+				t.Errorf("%s of the %s invoice left it %s, issued at %v, want %s, issued at %v",
+					onConflict, name, invoice.Status, invoice.IssuedAt, status, issuedAt[name])
+			}
+			var customer string
+			if err := tx.QueryRow(`SELECT customer_name FROM invoices WHERE invoice_no = $1`, invoice.InvoiceNo).Scan(&customer); err != nil || customer != "New customer" {
+				t.Errorf("%s of the %s invoice stored customer %q, %v, want New customer", onConflict, name, customer, err)
+			}
+		}
+		for _, name := range []string{"paid", "void"} {
+			invoice := newInvoice(prefix+name, "New customer")
+			if _, _, err := SaveInvoice(tx, &invoice, onConflict); !errors.Is(err, ErrInvoiceNotEditable) {
+				t.Errorf("%s of the %s invoice: err = %v, want ErrInvoiceNotEditable", onConflict, name, err)
+			}
+		}
+		tx.Rollback()
+	}
+}
+
+// TestInsertInvoices checks that a batch of prepared invoices is inserted with the IDs of the invoices
+// and of their products, and that a batch with a number already taken is refused as a whole.
+func TestInsertInvoices(t *testing.T) {
+	db := testDB(t)
+	prefix := fmt.Sprintf("TEST-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+
+	lookups, err := LoadInvoiceLookups(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepare := func(names ...string) []*models.Invoice {
+		var invoices []*models.Invoice
+		for _, name := range names {
+			invoice := &models.Invoice{
+				InvoiceNo:       prefix + name,
+				Date:            time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				CustomerName:    "Customer",
+				SalespersonName: "Salesperson",
+				PaymentType:     "CREDIT",
+				Products: []models.Product{
+					{ItemName: "Product 1", Quantity: 1, TotalCost: 100, TotalPrice: 150},
+					{ItemName: "Product 2", Quantity: 2, TotalCost: 200, TotalPrice: 300},
+				},
+			}
+			if _, err := PrepareInvoice(lookups, invoice); err != nil {
+				t.Fatalf("PrepareInvoice(%s): %v", invoice.InvoiceNo, err)
+			}
+			invoices = append(invoices, invoice)
+		}
+		return invoices
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	invoices := prepare("a", "b", "c")
+	if err := InsertInvoices(tx, invoices); err != nil {
+		t.Fatal(err)
+	}
+	for _, invoice := range invoices {
+		var id int
+		var dueDate *time.Time
+		if err := tx.QueryRow(`SELECT id, due_date FROM invoices WHERE invoice_no = $1`, invoice.InvoiceNo).Scan(&id, &dueDate); err != nil {
+			t.Fatal(err)
+		}
+		if id == 0 || id != invoice.ID || dueDate == nil {
+			t.Errorf("%s stored with id %d and due date %v, want id %d and the default terms", invoice.InvoiceNo, id, dueDate, invoice.ID)
+		}
+		for _, product := range invoice.Products {
+			var invoiceNo string
+			if err := tx.QueryRow(`SELECT invoice_no FROM products WHERE id = $1 AND item_name = $2`, product.ID, product.ItemName).Scan(&invoiceNo); err != nil || invoiceNo != invoice.InvoiceNo {
+				t.Errorf("product %d of %s stored under %q, %v", product.ID, invoice.InvoiceNo, invoiceNo, err)
+			}
+		}
+	}
+
+	if err := InsertInvoices(tx, prepare("d", "b")); !errors.Is(err, ErrInvoiceExists) {
+		t.Errorf("inserting a batch with a taken number: err = %v, want ErrInvoiceExists", err)
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+)
+
+// GetNumberSequences retrieves all number sequences ordered by name.
+func GetNumberSequences(db *sql.DB) ([]models.NumberSequence, error) {
+	rows, err := db.Query(`SELECT name, pattern, updated_at FROM number_sequences ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sequences := []models.NumberSequence{}
+	for rows.Next() {
+		var sequence models.NumberSequence
+		if err := rows.Scan(&sequence.Name, &sequence.Pattern, &sequence.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, sequence)
+	}
+	return sequences, rows.Err()
+}
+
+// GetNumberSequence retrieves a single number sequence. It returns sql.ErrNoRows if the name doesn't exist.
+func GetNumberSequence(q Querier, name string) (models.NumberSequence, error) {
+	var sequence models.NumberSequence
+	err := q.QueryRow(`SELECT name, pattern, updated_at FROM number_sequences WHERE name = $1`, name).
+		Scan(&sequence.Name, &sequence.Pattern, &sequence.UpdatedAt)
+	return sequence, err
+}
+
+// UpdateNumberSequence changes the pattern of a number sequence. Counters of periods the
+// new pattern shares with the old one carry on, so numbers aren't handed out twice.
+func UpdateNumberSequence(db *sql.DB, sequence *models.NumberSequence) error {
+	sqlQuery := `UPDATE number_sequences SET pattern = $1, updated_at = NOW() WHERE name = $2 RETURNING updated_at`
+	return db.QueryRow(sqlQuery, sequence.Pattern, sequence.Name).Scan(&sequence.UpdatedAt)
+}
+
+// sequenceTaken reports whether a number is already in use by what a sequence numbers, by sequence name.
+var sequenceTaken = map[string]func(q Querier, number string) (bool, error){
+	models.SequenceInvoice: CheckInvoiceExists,
+}
+
+// NextNumber hands out the next free number of a sequence for date, skipping numbers a client already
+// took by hand. It runs in a short transaction of its own, so the counter row is only locked while the
+// number is picked and not while the caller uses it. Concurrent callers never get the same number, but
+// a number whose use fails is not handed out again.
+func NextNumber(db *sql.DB, name string, date time.Time) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	sequence, err := GetNumberSequence(tx, name)
+	if err != nil {
+		return "", err
+	}
+
+	period := utils.SequencePeriod(sequence.Pattern, date)
+	sqlQuery := `INSERT INTO number_sequence_counters (name, period, last_value)
+	             VALUES ($1, $2, 1)
+	             ON CONFLICT (name, period) DO UPDATE SET last_value = number_sequence_counters.last_value + 1
+	             RETURNING last_value`
+	for {
+		var value int64
+		if err := tx.QueryRow(sqlQuery, name, period).Scan(&value); err != nil {
+			return "", err
+		}
+		number := utils.FormatSequenceNumber(sequence.Pattern, date, value)
+		if taken := sequenceTaken[name]; taken != nil {
+			if inUse, err := taken(tx, number); err != nil {
+				return "", err
+			} else if inUse {
+				continue
+			}
+		}
+		return number, tx.Commit()
+	}
+}
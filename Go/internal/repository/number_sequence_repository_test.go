@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+)
+
+// TestNextNumberSkipsTakenNumbers checks that numbers a client took by hand are skipped rather than
+// generated again, and that the counter isn't held by a transaction still using a number.
+func TestNextNumberSkipsTakenNumbers(t *testing.T) {
+	db := testDB(t)
+	sequence, err := GetNumberSequence(db, models.SequenceInvoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A month of its own in the past keeps the counter apart from the invoices of other tests
+	date := time.Date(1900+int(time.Now().UnixNano()%100), time.Month(1+time.Now().UnixNano()%12), 1, 0, 0, 0, 0, time.UTC)
+	period := utils.SequencePeriod(sequence.Pattern, date)
+	if period == "" {
+		t.Skipf("the invoice sequence %s has no date tokens", sequence.Pattern)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE date = $1`, date)
+		db.Exec(`DELETE FROM number_sequence_counters WHERE name = $1 AND period = $2`, models.SequenceInvoice, period)
+	})
+
+	for _, value := range []int64{1, 2} {
+		invoice := models.Invoice{
+			InvoiceNo:       utils.FormatSequenceNumber(sequence.Pattern, date, value),
+			Date:            date,
+			CustomerName:    "Customer",
+			SalespersonName: "Salesperson",
+			PaymentType:     "CASH",
+			Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+		}
+		if _, err := CreateInvoice(db, &invoice); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A transaction inserting an invoice keeps its number without blocking the next one
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	invoice := models.Invoice{
+		Date:            date,
+		CustomerName:    "Customer",
+		SalespersonName: "Salesperson",
+		PaymentType:     "CASH",
+		Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+	}
+	if invoice.InvoiceNo, err = NextNumber(db, models.SequenceInvoice, date); err != nil {
+		t.Fatal(err)
+	}
+	if want := utils.FormatSequenceNumber(sequence.Pattern, date, 3); invoice.InvoiceNo != want {
+		t.Errorf("NextNumber = %s, want %s after the numbers taken by hand", invoice.InvoiceNo, want)
+	}
+	if _, err := InsertInvoice(tx, &invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan string, 1)
+	go func() {
+		number, err := NextNumber(db, models.SequenceInvoice, date)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- number
+	}()
+	select {
+	case number := <-done:
+		if want := utils.FormatSequenceNumber(sequence.Pattern, date, 4); number != want {
+			t.Errorf("NextNumber = %s, want %s", number, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("NextNumber waited on a transaction using an earlier number")
+	}
+}
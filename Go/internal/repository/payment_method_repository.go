@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"widatech-technical-challenge/internal/models"
+)
+
+var (
+	// ErrPaymentMethodInUse is returned when deleting a payment method that invoices still reference.
+	ErrPaymentMethodInUse = errors.New("payment method is used by existing invoices")
+	// ErrPaymentMethodExists is returned when creating a payment method whose code is already taken.
+	ErrPaymentMethodExists = errors.New("payment method already exists")
+	// ErrPaymentMethodCreditInUse is returned when changing whether a payment method is paid later while
+	// invoices use it, which would change their due dates, payment status and cash totals after the fact.
+	ErrPaymentMethodCreditInUse = errors.New("payment method is used by existing invoices, so is_credit can't change")
+)
+
+// GetPaymentMethods retrieves all payment methods ordered by code.
+func GetPaymentMethods(db *sql.DB) ([]models.PaymentMethod, error) {
+	rows, err := db.Query(`SELECT code, name, active, is_credit FROM payment_methods ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paymentMethods := []models.PaymentMethod{}
+	for rows.Next() {
+		var paymentMethod models.PaymentMethod
+		if err := rows.Scan(&paymentMethod.Code, &paymentMethod.Name, &paymentMethod.Active, &paymentMethod.IsCredit); err != nil {
+			return nil, err
+		}
+		paymentMethods = append(paymentMethods, paymentMethod)
+	}
+	return paymentMethods, rows.Err()
+}
+
+// GetPaymentMethod retrieves a single payment method by code. It returns sql.ErrNoRows if the code doesn't exist.
+func GetPaymentMethod(db *sql.DB, code string) (models.PaymentMethod, error) {
+	var paymentMethod models.PaymentMethod
+	err := db.QueryRow(`SELECT code, name, active, is_credit FROM payment_methods WHERE code = $1`, code).
+		Scan(&paymentMethod.Code, &paymentMethod.Name, &paymentMethod.Active, &paymentMethod.IsCredit)
+	return paymentMethod, err
+}
+
+// GetActivePaymentMethodCodes returns the codes invoices may currently use, for validation.
+func GetActivePaymentMethodCodes(q Querier) (map[string]bool, error) {
+	rows, err := q.Query(`SELECT code FROM payment_methods WHERE active`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := map[string]bool{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes[code] = true
+	}
+	return codes, rows.Err()
+}
+
+// IsCreditPaymentMethod reports whether invoices using the payment method are paid later.
+func IsCreditPaymentMethod(q Querier, code string) (bool, error) {
+	var isCredit bool
+	err := q.QueryRow(`SELECT is_credit FROM payment_methods WHERE code = $1`, code).Scan(&isCredit)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isCredit, err
+}
+
+// CreatePaymentMethod inserts a new payment method. It returns ErrPaymentMethodExists if the code is already taken.
+func CreatePaymentMethod(db *sql.DB, paymentMethod models.PaymentMethod) error {
+	sqlQuery := `INSERT INTO payment_methods (code, name, active, is_credit) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(sqlQuery, paymentMethod.Code, paymentMethod.Name, paymentMethod.Active, paymentMethod.IsCredit)
+	if isUniqueViolation(err) {
+		return ErrPaymentMethodExists
+	}
+	return err
+}
+
+// UpdatePaymentMethod updates the name, active and credit flags of a payment method. The credit flag
+// can only change while no invoice uses the method: it returns ErrPaymentMethodCreditInUse otherwise.
+func UpdatePaymentMethod(db *sql.DB, paymentMethod models.PaymentMethod) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The lock keeps invoices from taking up the method between the check and the update
+	var isCredit, inUse bool
+	err = tx.QueryRow(`SELECT is_credit, EXISTS (SELECT 1 FROM invoices WHERE payment_type = $1)
+	                   FROM payment_methods WHERE code = $1
+	                   FOR UPDATE`, paymentMethod.Code).Scan(&isCredit, &inUse)
+	if err != nil {
+		return err
+	}
+	if isCredit != paymentMethod.IsCredit && inUse {
+		return ErrPaymentMethodCreditInUse
+	}
+
+	sqlQuery := `UPDATE payment_methods SET name = $1, active = $2, is_credit = $3 WHERE code = $4`
+	if _, err := tx.Exec(sqlQuery, paymentMethod.Name, paymentMethod.Active, paymentMethod.IsCredit, paymentMethod.Code); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeletePaymentMethod removes a payment method that no invoice references.
+func DeletePaymentMethod(db *sql.DB, code string) error {
+	result, err := db.Exec(`DELETE FROM payment_methods WHERE code = $1`, code)
+	if isForeignKeyViolation(err) {
+		return ErrPaymentMethodInUse
+	}
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
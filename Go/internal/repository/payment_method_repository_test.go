@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+// TestPaymentMethodConflicts checks that a taken code can't be created again, and that is_credit only
+// changes while no invoice uses the method.
+func TestPaymentMethodConflicts(t *testing.T) {
+	db := testDB(t)
+	code := fmt.Sprintf("T%d", time.Now().UnixNano()%1e9)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE payment_type = $1`, code)
+		db.Exec(`DELETE FROM payment_methods WHERE code = $1`, code)
+	})
+
+	method := models.PaymentMethod{Code: code, Name: "Test", Active: true}
+	if err := CreatePaymentMethod(db, method); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreatePaymentMethod(db, method); !errors.Is(err, ErrPaymentMethodExists) {
+		t.Errorf("creating %s again: err = %v, want ErrPaymentMethodExists", code, err)
+	}
+
+	// Unused, the method may become a credit method and back
+	method.IsCredit = true
+	if err := UpdatePaymentMethod(db, method); err != nil {
+		t.Fatalf("setting is_credit on an unused method: %v", err)
+	}
+	method.IsCredit = false
+	if err := UpdatePaymentMethod(db, method); err != nil {
+		t.Fatalf("clearing is_credit on an unused method: %v", err)
+	}
+
+	invoice := models.Invoice{
+		InvoiceNo:       "TEST-" + code,
+		Date:            time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		CustomerName:    "Customer",
+		SalespersonName: "Salesperson",
+		PaymentType:     code,
+		Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+	}
+	if _, err := CreateInvoice(db, &invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	method.IsCredit = true
+	if err := UpdatePaymentMethod(db, method); !errors.Is(err, ErrPaymentMethodCreditInUse) {
+		t.Errorf("setting is_credit on a method in use: err = %v, want ErrPaymentMethodCreditInUse", err)
+	}
+	method.IsCredit, method.Name, method.Active = false, "Renamed", false
+	if err := UpdatePaymentMethod(db, method); err != nil {
+		t.Errorf("renaming and deactivating a method in use: %v", err)
+	}
+	if err := UpdatePaymentMethod(db, models.PaymentMethod{Code: code + "X", Name: "Missing"}); err != sql.ErrNoRows {
+		t.Errorf("updating a missing method: err = %v, want sql.ErrNoRows", err)
+	}
+}
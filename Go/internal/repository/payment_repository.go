@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+)
+
+var (
+	// ErrInvoiceNotOnCredit is returned when recording a payment on an invoice that was settled when it was created.
+	ErrInvoiceNotOnCredit = errors.New("invoice is not on a credit payment method")
+	// ErrInvalidPayment wraps validation failures of a payment.
+	ErrInvalidPayment = errors.New("invalid payment")
+)
+
+// GetInvoiceBalance returns the payment position of an invoice as of today.
+// It returns sql.ErrNoRows if the invoice doesn't exist.
+func GetInvoiceBalance(q Querier, invoiceNo string) (models.InvoiceBalance, error) {
+	balance, _, _, err := getInvoiceBalance(q, invoiceNo, false)
+	return balance, err
+}
+
+// getInvoiceBalance loads the balance of an invoice along with its date and whether it is on credit.
+// With lock set, the invoice row stays locked until the transaction ends so concurrent payments serialize.
+func getInvoiceBalance(q Querier, invoiceNo string, lock bool) (balance models.InvoiceBalance, invoiceDate time.Time, isCredit bool, err error) {
+	sqlQuery := `SELECT i.invoice_no, i.status, i.date, i.total_amount, i.due_date, m.is_credit,
+	                    (SELECT COALESCE(SUM(c.total_amount), 0) FROM credit_notes c WHERE c.invoice_no = i.invoice_no),
+	                    (SELECT COALESCE(SUM(p.amount), 0) FROM payments p WHERE p.invoice_no = i.invoice_no)
+	             FROM invoices i
+	             JOIN payment_methods m ON m.code = i.payment_type
+	             WHERE i.invoice_no = $1`
+	if lock {
+		sqlQuery += ` FOR UPDATE OF i`
+	}
+
+	err = q.QueryRow(sqlQuery, invoiceNo).
+		Scan(&balance.InvoiceNo, &balance.Status, &invoiceDate, &balance.TotalAmount, &balance.DueDate, &isCredit, &balance.CreditedAmount, &balance.PaidAmount)
+	if err != nil {
+		return balance, invoiceDate, isCredit, err
+	}
+
+	// Credit notes reduce what the customer owes
+	if isCredit {
+		balance.OutstandingAmount = balance.TotalAmount - balance.CreditedAmount - balance.PaidAmount
+	}
+	balance.PaymentStatus = utils.PaymentStatus(balance.TotalAmount-balance.CreditedAmount, balance.PaidAmount, balance.DueDate, isCredit, time.Now())
+	return balance, invoiceDate, isCredit, nil
+}
+
+// GetPayments retrieves the payments recorded on an invoice, oldest first.
+func GetPayments(db *sql.DB, invoiceNo string) ([]models.Payment, error) {
+	sqlQuery := `SELECT id, invoice_no, amount, paid_at, COALESCE(payment_method, ''), COALESCE(reference, ''), COALESCE(notes, '')
+	             FROM payments
+	             WHERE invoice_no = $1
+	             ORDER BY paid_at, id`
+	rows, err := db.Query(sqlQuery, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		var payment models.Payment
+		if err := rows.Scan(&payment.ID, &payment.InvoiceNo, &payment.Amount, &payment.PaidAt, &payment.PaymentMethod, &payment.Reference, &payment.Notes); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// CreatePayment records a partial or full payment on an issued credit invoice and sets its ID.
+// The payment may not exceed the outstanding balance. It returns sql.ErrNoRows if the invoice doesn't exist.
+func CreatePayment(db *sql.DB, payment *models.Payment) (models.InvoiceBalance, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.InvoiceBalance{}, err
+	}
+	defer tx.Rollback()
+
+	balance, invoiceDate, isCredit, err := getInvoiceBalance(tx, payment.InvoiceNo, true)
+	if err != nil {
+		return balance, err
+	}
+	if balance.Status != models.InvoiceStatusIssued {
+		return balance, fmt.Errorf("%w: payments can only be recorded on issued invoices", ErrInvalidPayment)
+	}
+	if !isCredit {
+		return balance, ErrInvoiceNotOnCredit
+	}
+	if err := utils.ValidatePayment(*payment, balance, invoiceDate); err != nil {
+		return balance, fmt.Errorf("%w: %v", ErrInvalidPayment, err)
+	}
+
+	sqlQuery := `INSERT INTO payments (invoice_no, amount, paid_at, payment_method, reference, notes)
+	             VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+	             RETURNING id`
+	err = tx.QueryRow(sqlQuery, payment.InvoiceNo, payment.Amount, payment.PaidAt, payment.PaymentMethod, payment.Reference, payment.Notes).
+		Scan(&payment.ID)
+	if isForeignKeyViolation(err) {
+		return balance, fmt.Errorf("%w: unknown payment method %s", ErrInvalidPayment, payment.PaymentMethod)
+	}
+	if err != nil {
+		return balance, err
+	}
+	if err := tx.Commit(); err != nil {
+		return balance, err
+	}
+
+	balance.PaidAmount += payment.Amount
+	balance.OutstandingAmount -= payment.Amount
+	balance.PaymentStatus = utils.PaymentStatus(balance.TotalAmount-balance.CreditedAmount, balance.PaidAmount, balance.DueDate, isCredit, time.Now())
+	return balance, nil
+}
+
+// DeletePayment removes a payment recorded on an invoice.
+func DeletePayment(db *sql.DB, invoiceNo string, id int) error {
+	result, err := db.Exec(`DELETE FROM payments WHERE invoice_no = $1 AND id = $2`, invoiceNo, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// GetOpenCreditInvoices returns the issued credit invoices dated on or before asOf that still had an
+// outstanding balance on that date, counting only payments received and credit notes raised by then.
+func GetOpenCreditInvoices(db *sql.DB, asOf time.Time) ([]models.AgingInvoice, error) {
+	sqlQuery := `SELECT invoice_no, customer_name, date, due_date, currency, outstanding
+	             FROM (
+	                 SELECT i.invoice_no, i.customer_name, i.date, COALESCE(i.due_date, i.date) AS due_date, i.currency,
+	                        i.total_amount - COALESCE((SELECT SUM(p.amount) FROM payments p
+	                                                   WHERE p.invoice_no = i.invoice_no AND p.paid_at <= $1), 0)
+	                                       - COALESCE((SELECT SUM(c.total_amount) FROM credit_notes c
+	                                                   WHERE c.invoice_no = i.invoice_no AND c.date <= $1), 0) AS outstanding
+	                 FROM invoices i
+	                 JOIN payment_methods m ON m.code = i.payment_type
+	                 WHERE m.is_credit AND i.status = 'issued' AND i.date <= $1
+	             ) open_invoices
+	             WHERE outstanding > 0
+	             ORDER BY customer_name, due_date, invoice_no`
+	rows, err := db.Query(sqlQuery, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []models.AgingInvoice{}
+	for rows.Next() {
+		var invoice models.AgingInvoice
+		if err := rows.Scan(&invoice.InvoiceNo, &invoice.CustomerName, &invoice.Date, &invoice.DueDate, &invoice.Currency, &invoice.OutstandingAmount); err != nil {
+			return nil, err
+		}
+		invoice.DaysPastDue = utils.DaysBetween(invoice.DueDate, asOf)
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+// TestGetOpenCreditInvoicesIssuedOnly checks that the aging report leaves drafts and void invoices out,
+// whatever they still show as owed.
+func TestGetOpenCreditInvoicesIssuedOnly(t *testing.T) {
+	db := testDB(t)
+	prefix := fmt.Sprintf("TEST-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, status := range []string{models.InvoiceStatusDraft, models.InvoiceStatusIssued, models.InvoiceStatusVoid} {
+		invoice := models.Invoice{
+			InvoiceNo:       prefix + status,
+			Date:            date,
+			CustomerName:    "Customer",
+			SalespersonName: "Salesperson",
+			PaymentType:     "CREDIT",
+			Status:          models.InvoiceStatusDraft,
+			Products:        []models.Product{{ItemName: "Product", Quantity: 1, TotalCost: 100, TotalPrice: 150}},
+		}
+		if status != models.InvoiceStatusDraft {
+			invoice.Status = models.InvoiceStatusIssued
+		}
+		if _, err := CreateInvoice(db, &invoice); err != nil {
+			t.Fatal(err)
+		}
+		if status == models.InvoiceStatusVoid {
+			if err := VoidInvoice(db, invoice.InvoiceNo, "Sent to the wrong customer"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	invoices, err := GetOpenCreditInvoices(db, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var open []string
+	for _, invoice := range invoices {
+		if strings.HasPrefix(invoice.InvoiceNo, prefix) {
+			open = append(open, invoice.InvoiceNo)
+		}
+	}
+	if len(open) != 1 || open[0] != prefix+models.InvoiceStatusIssued {
+		t.Errorf("open credit invoices = %v, want only %s", open, prefix+models.InvoiceStatusIssued)
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+)
+
+// AddProduct adds a product to a draft invoice, sets its ID and recomputes the invoice totals.
+// It returns the warnings raised by the validation rules, or sql.ErrNoRows if the invoice doesn't exist.
+func AddProduct(db *sql.DB, invoiceNo string, product *models.Product) ([]models.Issue, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	taxMode, err := lockDraftInvoice(tx, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareProduct(tx, product, taxMode); err != nil {
+		return nil, err
+	}
+	if err := insertProduct(tx, invoiceNo, product); err != nil {
+		return nil, err
+	}
+	lines, err := updateInvoiceTotals(tx, invoiceNo, taxMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the warning rules against the invoice as it now stands; strict mode rolls the change back
+	issues := utils.ValidateProductChange(*product, lines)
+	if err := utils.IssuesError(issues); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return utils.Warnings(issues), nil
+}
+
+// UpdateProduct replaces a product of a draft invoice and recomputes the invoice totals.
+// It returns the warnings raised by the validation rules, or sql.ErrNoRows if the invoice or the product doesn't exist.
+func UpdateProduct(db *sql.DB, invoiceNo string, product *models.Product) ([]models.Issue, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	taxMode, err := lockDraftInvoice(tx, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareProduct(tx, product, taxMode); err != nil {
+		return nil, err
+	}
+
+	if err := updateProduct(tx, invoiceNo, product); err != nil {
+		return nil, err
+	}
+
+	lines, err := updateInvoiceTotals(tx, invoiceNo, taxMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the warning rules against the invoice as it now stands; strict mode rolls the change back
+	issues := utils.ValidateProductChange(*product, lines)
+	if err := utils.IssuesError(issues); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return utils.Warnings(issues), nil
+}
+
+// DeleteProduct removes a product from a draft invoice and recomputes the invoice totals.
+// It returns sql.ErrNoRows if the invoice or the product doesn't exist.
+func DeleteProduct(db *sql.DB, invoiceNo string, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	taxMode, err := lockDraftInvoice(tx, invoiceNo)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM products WHERE invoice_no = $1 AND id = $2`, invoiceNo, id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if _, err := updateInvoiceTotals(tx, invoiceNo, taxMode); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockDraftInvoice locks an invoice whose products are about to change and returns its tax mode.
+// Only drafts can have their products changed.
+func lockDraftInvoice(tx *sql.Tx, invoiceNo string) (string, error) {
+	status, taxMode, err := lockInvoice(tx, invoiceNo)
+	if err != nil {
+		return "", err
+	}
+	if status != models.InvoiceStatusDraft {
+		return "", fmt.Errorf("%w: products of %s invoices can't be changed", ErrInvoiceNotEditable, status)
+	}
+	return taxMode, nil
+}
+
+// updateInvoiceTotals recomputes the tax and total amount stored on an invoice from its products,
+// and returns the products.
+func updateInvoiceTotals(q Querier, invoiceNo, taxMode string) ([]models.Product, error) {
+	products, err := getInvoiceProducts(q, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := models.Invoice{TaxMode: taxMode, Products: products}
+	for _, product := range products {
+		invoice.TaxTotal += product.TaxAmount
+	}
+	invoice.TotalAmount = utils.InvoiceTotalAmount(invoice)
+
+	_, err = q.Exec(`UPDATE invoices SET tax_total = $1, total_amount = $2 WHERE invoice_no = $3`, invoice.TaxTotal, invoice.TotalAmount, invoiceNo)
+	return products, err
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "database/sql"
+
+// Querier is satisfied by both *sql.DB and *sql.Tx, so lookups can run inside a transaction.
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
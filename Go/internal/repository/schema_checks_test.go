@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/lib/pq"
+)
+
+// serverSetChecks are the CHECK constraints on values the server computes or takes from its
+// configuration rather than from a request, so no rule mirrors them.
+var serverSetChecks = map[string]bool{
+	"invoices_tax_total_check":            true,
+	"invoices_total_amount_check":         true,
+	"products_tax_rate_check":             true,
+	"products_tax_amount_check":           true,
+	"exchange_rates_base_currency_check":  true, // BASE_CURRENCY
+	"credit_notes_tax_total_check":        true,
+	"credit_notes_total_amount_check":     true,
+	"credit_note_lines_total_cost_check":  true,
+	"credit_note_lines_total_price_check": true,
+	"credit_note_lines_tax_amount_check":  true,
+}
+
+// TestRulesMatchSchemaChecks breaks each CHECK constraint of the migrated schema that guards a value
+// from a request, and checks that the rule mirroring it rejects the same value, so bad data is
+// reported before it reaches the database. A CHECK added without a case here fails the test.
+func TestRulesMatchSchemaChecks(t *testing.T) {
+	db := testDB(t)
+	invoiceNo := fmt.Sprintf("TEST-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no = $1`, invoiceNo)
+	})
+
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	terms := 30
+	seed := models.Invoice{
+		InvoiceNo:       invoiceNo,
+		Date:            date,
+		CustomerName:    "John",
+		SalespersonName: "Doe",
+		PaymentType:     "CREDIT",
+		PaymentTerms:    &terms,
+		Notes:           "Lorem ipsum",
+		Products: []models.Product{{
+			ItemName:   "Bluetooth speaker",
+			Quantity:   2,
+			UnitCost:   5000,
+			UnitPrice:  7500,
+			TotalCost:  10000,
+			TotalPrice: 15000,
+		}},
+	}
+	if _, err := CreateInvoice(db, &seed); err != nil {
+		t.Fatal(err)
+	}
+	productID := seed.Products[0].ID
+
+	// invoiceRule returns the error the invoice rules report on field once change is applied to the
+	// seeded invoice, or nil if they report none.
+	invoiceRule := func(field string, change func(i *models.Invoice)) error {
+		invoice := seed
+		invoice.Products = append([]models.Product{}, seed.Products...)
+		change(&invoice)
+		for _, issue := range utils.ValidateInvoice(invoice, map[string]bool{"CREDIT": true}) {
+			if issue.Field == field && issue.Severity == models.SeverityError {
+				return errors.New(issue.Message)
+			}
+		}
+		return nil
+	}
+	updateInvoice := `UPDATE invoices SET %s WHERE invoice_no = $1`
+	updateProduct := `UPDATE products SET %s WHERE id = $1`
+	insertTaxRate := `INSERT INTO tax_rates (code, name, rate) VALUES ($1, $2, $3)`
+	insertExchangeRate := `INSERT INTO exchange_rates (base_currency, currency, rate_date, rate) VALUES ($1, $2, $3, $4)`
+	insertPaymentMethod := `INSERT INTO payment_methods (code, name) VALUES ($1, $2)`
+
+	tests := []struct {
+		constraint string        // CHECK constraint the statement breaks
+		sqlQuery   string        // Statement breaking it, run in a transaction that is rolled back
+		args       []interface{} // Arguments of the statement
+		rule       error         // What the rule mirroring the constraint returns for the same value
+	}{
+		// invoices
+		{"invoices_customer_name_check", fmt.Sprintf(updateInvoice, "customer_name = $2"), []interface{}{invoiceNo, "É"},
+			invoiceRule("customer_name", func(i *models.Invoice) { i.CustomerName = "É" })},
+		{"invoices_salesperson_name_check", fmt.Sprintf(updateInvoice, "salesperson_name = $2"), []interface{}{invoiceNo, "É"},
+			invoiceRule("salesperson_name", func(i *models.Invoice) { i.SalespersonName = "É" })},
+		{"chk_notes_length", fmt.Sprintf(updateInvoice, "notes = $2"), []interface{}{invoiceNo, "Noté"},
+			invoiceRule("notes", func(i *models.Invoice) { i.Notes = "Noté" })},
+		{"invoices_tax_mode_check", fmt.Sprintf(updateInvoice, "tax_mode = $2"), []interface{}{invoiceNo, "gross"},
+			invoiceRule("tax_mode", func(i *models.Invoice) { i.TaxMode = "gross" })},
+		{"invoices_currency_check", fmt.Sprintf(updateInvoice, "currency = $2"), []interface{}{invoiceNo, "usd"},
+			invoiceRule("currency", func(i *models.Invoice) { i.Currency = "usd" })},
+		{"invoices_payment_terms_days_check", fmt.Sprintf(updateInvoice, "payment_terms_days = $2"), []interface{}{invoiceNo, -1},
+			invoiceRule("payment_terms_days", func(i *models.Invoice) { terms := -1; i.PaymentTerms = &terms })},
+		{"chk_due_date", fmt.Sprintf(updateInvoice, "due_date = $2"), []interface{}{invoiceNo, date.AddDate(0, 0, -1)},
+			invoiceRule("due_date", func(i *models.Invoice) { dueDate := date.AddDate(0, 0, -1); i.DueDate = &dueDate })},
+		{"invoices_status_check", fmt.Sprintf(updateInvoice, "status = $2"), []interface{}{invoiceNo, "paid"},
+			invoiceRule("status", func(i *models.Invoice) { i.Status = "paid" })},
+		{"chk_void_reason", fmt.Sprintf(updateInvoice, "status = 'void', void_reason = $2"), []interface{}{invoiceNo, "Typé"},
+			VoidInvoice(db, invoiceNo, "Typé")},
+
+		// products
+		{"products_item_name_check", fmt.Sprintf(updateProduct, "item_name = $2"), []interface{}{productID, "Café"},
+			invoiceRule("products[0].item_name", func(i *models.Invoice) { i.Products[0].ItemName = "Café" })},
+		{"products_quantity_check", fmt.Sprintf(updateProduct, "quantity = $2"), []interface{}{productID, 0},
+			invoiceRule("products[0].quantity", func(i *models.Invoice) { i.Products[0].Quantity = 0 })},
+		{"products_total_cost_check", fmt.Sprintf(updateProduct, "total_cost = $2"), []interface{}{productID, "-0.01"},
+			invoiceRule("products[0].total_cost", func(i *models.Invoice) { i.Products[0].TotalCost = -1 })},
+		{"products_total_price_check", fmt.Sprintf(updateProduct, "total_price = $2"), []interface{}{productID, "-0.01"},
+			invoiceRule("products[0].total_price", func(i *models.Invoice) { i.Products[0].TotalPrice = -1 })},
+		{"products_unit_cost_check", fmt.Sprintf(updateProduct, "unit_cost = $2"), []interface{}{productID, "-0.01"},
+			invoiceRule("products[0].unit_cost", func(i *models.Invoice) { i.Products[0].UnitCost = -1 })},
+		{"products_unit_price_check", fmt.Sprintf(updateProduct, "unit_price = $2"), []interface{}{productID, "-0.01"},
+			invoiceRule("products[0].unit_price", func(i *models.Invoice) { i.Products[0].UnitPrice = -1 })},
+		{"products_discount_amount_check", fmt.Sprintf(updateProduct, "discount_amount = $2"), []interface{}{productID, "-0.01"},
+			invoiceRule("products[0].discount_amount", func(i *models.Invoice) { i.Products[0].DiscountAmount = -1 })},
+		{"products_discount_percent_check", fmt.Sprintf(updateProduct, "discount_percent = $2"), []interface{}{productID, "100.01"},
+			invoiceRule("products[0].discount_percent", func(i *models.Invoice) { i.Products[0].DiscountPercent = 10001 })},
+
+		// tax_rates
+		{"tax_rates_code_check", insertTaxRate, []interface{}{"", "Test", "11"},
+			utils.ValidateTaxRate(models.TaxRate{Code: "", Name: "Test", Rate: 1100})},
+		{"tax_rates_name_check", insertTaxRate, []interface{}{"TEST", "É", "11"},
+			utils.ValidateTaxRate(models.TaxRate{Code: "TEST", Name: "É", Rate: 1100})},
+		{"tax_rates_rate_check", insertTaxRate, []interface{}{"TEST", "Test", "100.01"},
+			utils.ValidateTaxRate(models.TaxRate{Code: "TEST", Name: "Test", Rate: 10001})},
+
+		// exchange_rates
+		{"exchange_rates_currency_check", insertExchangeRate, []interface{}{"IDR", "US", date, "1"},
+			utils.ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "US", RateDate: date, Rate: models.RateOne})},
+		{"exchange_rates_rate_check", insertExchangeRate, []interface{}{"IDR", "USD", date, "0"},
+			utils.ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "USD", RateDate: date})},
+		{"chk_exchange_rates_pair", insertExchangeRate, []interface{}{"IDR", "IDR", date, "1"},
+			utils.ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "IDR", RateDate: date, Rate: models.RateOne})},
+
+		// payment_methods
+		{"payment_methods_code_check", insertPaymentMethod, []interface{}{"cash", "Cash"},
+			utils.ValidatePaymentMethod(models.PaymentMethod{Code: "cash", Name: "Cash"})},
+		{"payment_methods_name_check", insertPaymentMethod, []interface{}{"TEST", "É"},
+			utils.ValidatePaymentMethod(models.PaymentMethod{Code: "TEST", Name: "É"})},
+
+		// payments
+		{"payments_amount_check", `INSERT INTO payments (invoice_no, amount, paid_at) VALUES ($1, 0, $2)`, []interface{}{invoiceNo, date},
+			utils.ValidatePayment(models.Payment{Amount: 0, PaidAt: date}, models.InvoiceBalance{OutstandingAmount: 15000}, date)},
+
+		// credit_notes and credit_note_lines
+		{"credit_notes_reason_check", `INSERT INTO credit_notes (credit_note_no, invoice_no, date, reason) VALUES ($1, $1, $2, 'Casé')`,
+			[]interface{}{invoiceNo, date},
+			binding.Validator.ValidateStruct(models.CreditNoteRequest{
+				CreditNoteNo: invoiceNo, Date: date, Reason: "Casé", Lines: []models.CreditNoteLineRequest{{ProductID: productID, Quantity: 1}},
+			})},
+		{"credit_note_lines_quantity_check", `WITH c AS (INSERT INTO credit_notes (credit_note_no, invoice_no, date, reason)
+		                                                VALUES ($1, $1, $2, 'Returned') RETURNING id)
+		                                      INSERT INTO credit_note_lines (credit_note_id, product_id, quantity, total_cost, total_price)
+		                                      SELECT id, $3, 0, 0, 0 FROM c`, []interface{}{invoiceNo, date, productID},
+			utils.ProrateCreditNoteLine(&models.CreditNoteLine{ProductID: productID}, seed.Products[0], models.CreditNoteLine{})},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[tt.constraint] = true
+		if tt.rule == nil {
+			t.Errorf("%s: the rule accepts a value the constraint rejects", tt.constraint)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.Exec(tt.sqlQuery, tt.args...)
+		tx.Rollback()
+		var pqErr *pq.Error
+		switch {
+		case err == nil:
+			t.Errorf("%s: the statement succeeded, want the constraint to reject it", tt.constraint)
+		case !errors.As(err, &pqErr) || pqErr.Code != "23514":
+			t.Errorf("%s: got %v, want a check violation", tt.constraint, err)
+		case pqErr.Constraint != tt.constraint:
+			t.Errorf("%s: the statement broke %s instead", tt.constraint, pqErr.Constraint)
+		}
+	}
+
+	rows, err := db.Query(`SELECT conname FROM pg_constraint
+	                       WHERE contype = 'c'
+	                         AND conrelid::regclass::text IN ('invoices', 'products', 'tax_rates', 'exchange_rates',
+	                                                          'payment_methods', 'payments', 'credit_notes', 'credit_note_lines')
+	                       ORDER BY conname`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var constraint string
+		if err := rows.Scan(&constraint); err != nil {
+			t.Fatal(err)
+		}
+		if !covered[constraint] && !serverSetChecks[constraint] {
+			t.Errorf("%s has no case: add one with the rule that mirrors it", constraint)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"widatech-technical-challenge/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTaxRateInUse is returned when deleting a tax rate that product lines still reference.
+	ErrTaxRateInUse = errors.New("tax rate is used by existing products")
+	// ErrTaxRateExists is returned when creating a tax rate whose code is already taken.
+	ErrTaxRateExists = errors.New("tax rate already exists")
+)
+
+// GetTaxRates retrieves all tax rates ordered by code.
+func GetTaxRates(db *sql.DB) ([]models.TaxRate, error) {
+	rows, err := db.Query(`SELECT code, name, rate, active FROM tax_rates ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxRates := []models.TaxRate{}
+	for rows.Next() {
+		var taxRate models.TaxRate
+		if err := rows.Scan(&taxRate.Code, &taxRate.Name, &taxRate.Rate, &taxRate.Active); err != nil {
+			return nil, err
+		}
+		taxRates = append(taxRates, taxRate)
+	}
+	return taxRates, rows.Err()
+}
+
+// GetTaxRate retrieves a single tax rate by code. It returns sql.ErrNoRows if the code doesn't exist.
+func GetTaxRate(q Querier, code string) (models.TaxRate, error) {
+	var taxRate models.TaxRate
+	err := q.QueryRow(`SELECT code, name, rate, active FROM tax_rates WHERE code = $1`, code).
+		Scan(&taxRate.Code, &taxRate.Name, &taxRate.Rate, &taxRate.Active)
+	return taxRate, err
+}
+
+// CreateTaxRate inserts a new tax rate. It returns ErrTaxRateExists if the code is already taken.
+func CreateTaxRate(db *sql.DB, taxRate models.TaxRate) error {
+	sqlQuery := `INSERT INTO tax_rates (code, name, rate, active) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(sqlQuery, taxRate.Code, taxRate.Name, taxRate.Rate, taxRate.Active)
+	if isUniqueViolation(err) {
+		return ErrTaxRateExists
+	}
+	return err
+}
+
+// UpdateTaxRate updates the name, rate and active flag of a tax rate.
+// Products keep the rate they were created with.
+func UpdateTaxRate(db *sql.DB, taxRate models.TaxRate) error {
+	sqlQuery := `UPDATE tax_rates SET name = $1, rate = $2, active = $3 WHERE code = $4`
+	result, err := db.Exec(sqlQuery, taxRate.Name, taxRate.Rate, taxRate.Active, taxRate.Code)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DeleteTaxRate removes a tax rate that no product references.
+func DeleteTaxRate(db *sql.DB, code string) error {
+	result, err := db.Exec(`DELETE FROM tax_rates WHERE code = $1`, code)
+	if isForeignKeyViolation(err) {
+		return ErrTaxRateInUse
+	}
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// requireAffected returns sql.ErrNoRows when a statement matched no rows.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign_key_violation.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// WithSavepoint runs fn inside a savepoint of tx. If fn fails, the work it did is rolled back to
+// the savepoint and tx stays usable, so a batch can carry on past one bad item.
+func WithSavepoint(tx *sql.Tx, name string, fn func() error) error {
+	if _, err := tx.Exec(fmt.Sprintf("SAVEPOINT %s", name)); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rollbackErr := tx.Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name)); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	_, err := tx.Exec(fmt.Sprintf("RELEASE SAVEPOINT %s", name))
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"widatech-technical-challenge/internal/controllers"
 	"widatech-technical-challenge/internal/service"
+	"widatech-technical-challenge/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +14,19 @@ func RegisterRoutes(router *gin.Engine, db *sql.DB) {
 	// Initialize Services
 	invoiceService := service.NewInvoiceService(db)
 	importService := service.NewImportService(db)
+	importJobService := service.NewImportJobService(db, importService)
+	importProfileService := service.NewImportProfileService(db)
+	taxRateService := service.NewTaxRateService(db)
+	exchangeRateService := service.NewExchangeRateService(db)
+	reportService := service.NewReportService(db)
+	paymentMethodService := service.NewPaymentMethodService(db)
+	paymentService := service.NewPaymentService(db)
+	productService := service.NewProductService(db)
+	creditNoteService := service.NewCreditNoteService(db)
+	numberSequenceService := service.NewNumberSequenceService(db)
+
+	// Start the workers that process asynchronous imports
+	importJobService.Start(utils.ImportWorkers())
 
 	// Invoice
 	invoiceController := controllers.NewInvoiceController(invoiceService)
@@ -22,12 +36,106 @@ func RegisterRoutes(router *gin.Engine, db *sql.DB) {
 		invoiceRoutes.GET("/", invoiceController.GetInvoice)
 		invoiceRoutes.PUT("/", invoiceController.UpdateInvoice)
 		invoiceRoutes.DELETE("/:invoiceno", invoiceController.DeleteInvoice)
+		invoiceRoutes.POST("/:invoiceno/issue", invoiceController.IssueInvoice)
+		invoiceRoutes.POST("/:invoiceno/void", invoiceController.VoidInvoice)
+	}
+	// Invoice Products (drafts only)
+	productController := controllers.NewProductController(productService)
+	{
+		invoiceRoutes.POST("/:invoiceno/products", productController.AddProduct)
+		invoiceRoutes.PUT("/:invoiceno/products/:id", productController.UpdateProduct)
+		invoiceRoutes.DELETE("/:invoiceno/products/:id", productController.DeleteProduct)
+	}
+	// Invoice Payments
+	paymentController := controllers.NewPaymentController(paymentService)
+	{
+		invoiceRoutes.GET("/:invoiceno/payments", paymentController.GetPayments)
+		invoiceRoutes.POST("/:invoiceno/payments", paymentController.RecordPayment)
+		invoiceRoutes.DELETE("/:invoiceno/payments/:id", paymentController.DeletePayment)
+	}
+	// Invoice Credit Notes
+	creditNoteController := controllers.NewCreditNoteController(creditNoteService)
+	{
+		invoiceRoutes.GET("/:invoiceno/credit-notes", creditNoteController.GetCreditNotes)
+		invoiceRoutes.POST("/:invoiceno/credit-notes", creditNoteController.CreateCreditNote)
+	}
+	// Tax Rates
+	taxRateController := controllers.NewTaxRateController(taxRateService)
+	taxRateRoutes := router.Group("/api/tax-rates")
+	{
+		taxRateRoutes.GET("/", taxRateController.GetTaxRates)
+		taxRateRoutes.GET("/:code", taxRateController.GetTaxRate)
+		taxRateRoutes.POST("/", taxRateController.CreateTaxRate)
+		taxRateRoutes.PUT("/:code", taxRateController.UpdateTaxRate)
+		taxRateRoutes.DELETE("/:code", taxRateController.DeleteTaxRate)
+	}
+	// Payment Methods
+	paymentMethodController := controllers.NewPaymentMethodController(paymentMethodService)
+	paymentMethodRoutes := router.Group("/api/payment-methods")
+	{
+		paymentMethodRoutes.GET("/", paymentMethodController.GetPaymentMethods)
+		paymentMethodRoutes.GET("/:code", paymentMethodController.GetPaymentMethod)
+		paymentMethodRoutes.POST("/", paymentMethodController.CreatePaymentMethod)
+		paymentMethodRoutes.PUT("/:code", paymentMethodController.UpdatePaymentMethod)
+		paymentMethodRoutes.DELETE("/:code", paymentMethodController.DeletePaymentMethod)
+	}
+	// Number Sequences
+	numberSequenceController := controllers.NewNumberSequenceController(numberSequenceService)
+	numberSequenceRoutes := router.Group("/api/number-sequences")
+	{
+		numberSequenceRoutes.GET("/", numberSequenceController.GetNumberSequences)
+		numberSequenceRoutes.GET("/:name", numberSequenceController.GetNumberSequence)
+		numberSequenceRoutes.PUT("/:name", numberSequenceController.UpdateNumberSequence)
+	}
+	// Exchange Rates
+	exchangeRateController := controllers.NewExchangeRateController(exchangeRateService)
+	exchangeRateRoutes := router.Group("/api/exchange-rates")
+	{
+		exchangeRateRoutes.GET("/", exchangeRateController.GetExchangeRates)
+		exchangeRateRoutes.GET("/lookup", exchangeRateController.LookupExchangeRate)
+		exchangeRateRoutes.GET("/:id", exchangeRateController.GetExchangeRate)
+		exchangeRateRoutes.POST("/", exchangeRateController.CreateExchangeRate)
+		exchangeRateRoutes.PUT("/:id", exchangeRateController.UpdateExchangeRate)
+		exchangeRateRoutes.DELETE("/:id", exchangeRateController.DeleteExchangeRate)
+	}
+	// Reports
+	reportController := controllers.NewReportController(reportService)
+	reportRoutes := router.Group("/api/reports")
+	{
+		reportRoutes.GET("/sales", reportController.GetSalesReport)
+		reportRoutes.GET("/aging", reportController.GetAgingReport)
 	}
 	// XLSX Import Routes
-	xlsxController := controllers.NewImportController(importService) // Assuming you have an XLSX controller
+	xlsxController := controllers.NewImportController(importService, importJobService) // Assuming you have an XLSX controller
 	xlsxRoutes := router.Group("/api/xlsx")
 	{
 		// Route to upload an XLSX file
 		xlsxRoutes.POST("/import", xlsxController.ImportInvoices) // Handles the XLSX import
+		xlsxRoutes.GET("/template", xlsxController.GetImportTemplate)
+	}
+
+	// Import of XLSX workbooks and CSV files, detected from their content
+	importRoutes := router.Group("/api/import")
+	{
+		importRoutes.POST("", xlsxController.ImportInvoices)
+	}
+
+	// Import jobs queued with ?async=true
+	importJobController := controllers.NewImportJobController(importJobService)
+	{
+		importRoutes.GET("/jobs/:id", importJobController.GetImportJob)
+		importRoutes.POST("/jobs/:id/cancel", importJobController.CancelImportJob)
+		importRoutes.GET("/jobs/:id/report", importJobController.GetImportJobReport)
+	}
+
+	// Import profile routes
+	importProfileController := controllers.NewImportProfileController(importProfileService)
+	importProfileRoutes := router.Group("/api/import-profiles")
+	{
+		importProfileRoutes.GET("/", importProfileController.GetImportProfiles)
+		importProfileRoutes.GET("/:name", importProfileController.GetImportProfile)
+		importProfileRoutes.POST("/", importProfileController.CreateImportProfile)
+		importProfileRoutes.PUT("/:name", importProfileController.UpdateImportProfile)
+		importProfileRoutes.DELETE("/:name", importProfileController.DeleteImportProfile)
 	}
 }
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// CreditNoteService defines the service layer for credit notes on returned goods
+type CreditNoteService struct {
+	DB *sql.DB
+}
+
+// NewCreditNoteService creates a new CreditNoteService instance
+func NewCreditNoteService(db *sql.DB) *CreditNoteService {
+	return &CreditNoteService{DB: db}
+}
+
+// GetCreditNotes retrieves the credit notes raised against an invoice
+func (cs *CreditNoteService) GetCreditNotes(invoiceNo string) ([]models.CreditNote, error) {
+	exists, err := repository.CheckInvoiceExists(cs.DB, invoiceNo)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return repository.GetCreditNotes(cs.DB, invoiceNo)
+}
+
+// CreateCreditNote records goods returned against an invoice
+func (cs *CreditNoteService) CreateCreditNote(creditNote *models.CreditNote) error {
+	return repository.CreateCreditNote(cs.DB, creditNote)
+}
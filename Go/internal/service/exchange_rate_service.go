@@ -0,0 +1,49 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/utils"
+)
+
+// ExchangeRateService defines the service layer for exchange rate operations
+type ExchangeRateService struct {
+	DB *sql.DB
+}
+
+// NewExchangeRateService creates a new ExchangeRateService instance
+func NewExchangeRateService(db *sql.DB) *ExchangeRateService {
+	return &ExchangeRateService{DB: db}
+}
+
+// GetExchangeRates retrieves the rates into the base currency, optionally for one currency
+func (es *ExchangeRateService) GetExchangeRates(currency string) ([]models.ExchangeRate, error) {
+	return repository.GetExchangeRates(es.DB, utils.BaseCurrency(), currency)
+}
+
+// GetExchangeRate retrieves an exchange rate by its ID
+func (es *ExchangeRateService) GetExchangeRate(id int) (models.ExchangeRate, error) {
+	return repository.GetExchangeRate(es.DB, id)
+}
+
+// LookupExchangeRate retrieves the rate for a currency that applies on the given date
+func (es *ExchangeRateService) LookupExchangeRate(currency string, date time.Time) (models.ExchangeRate, error) {
+	return repository.FindExchangeRate(es.DB, utils.BaseCurrency(), currency, date)
+}
+
+// CreateExchangeRate creates a new exchange rate
+func (es *ExchangeRateService) CreateExchangeRate(exchangeRate *models.ExchangeRate) error {
+	return repository.CreateExchangeRate(es.DB, exchangeRate)
+}
+
+// UpdateExchangeRate updates an existing exchange rate
+func (es *ExchangeRateService) UpdateExchangeRate(exchangeRate models.ExchangeRate) error {
+	return repository.UpdateExchangeRate(es.DB, exchangeRate)
+}
+
+// DeleteExchangeRate deletes an exchange rate by its ID
+func (es *ExchangeRateService) DeleteExchangeRate(id int) error {
+	return repository.DeleteExchangeRate(es.DB, id)
+}
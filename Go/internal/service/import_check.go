@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// importPageSize is how many invoice rows an import reads from its staged rows at a time.
+const importPageSize = 500
+
+// importFile is a file staged in the database ahead of its import, so the problems in how its rows fit
+// together are found before any invoice is inserted without holding the file in memory. Its invoice rows
+// are read back a page at a time, with the product rows of their invoice numbers and the other rows the
+// same numbers are on.
+type importFile struct {
+	db                           *sql.DB
+	format                       string
+	id                           int64 // ID the rows are staged under
+	invoiceLayout, productLayout *sheetLayout
+	readErr                      *models.ImportError  // Error that stopped reading the invoice rows, reported after them
+	errors                       []models.ImportError // First product rows that can't be linked to an invoice number
+	unlinked                     int                  // Product rows that can't be linked, listed in errors or not
+
+	// The page of invoice rows being imported
+	page     []*rowReader
+	products productIndex
+	numbers  invoiceNumbers
+	existing map[string]bool // Invoice numbers of the page already taken when it was read
+	last     int             // Last row of the pages read
+	done     bool            // Every page has been read
+	err      error           // Error reading a page
+}
+
+// invoiceNumbers holds the rows of an invoice sheet that each invoice number is on.
+type invoiceNumbers map[string][]int
+
+// productIndex holds the product rows of a page of invoice rows by invoice number, in file order.
+type productIndex map[string][]*rowReader
+
+// lines parses the product rows of an invoice, each with a reader of its own so the errors of one
+// import attempt don't carry over to another invoice row with the same number.
+func (idx productIndex) lines(invoiceNo string) ([]models.Product, []*rowReader) {
+	var products []models.Product
+	var readers []*rowReader
+	for _, indexed := range idx[invoiceNo] {
+		r := newRowReader(indexed.layout, indexed.cells, indexed.raw, indexed.row)
+		products = append(products, parseProductRow(r))
+		readers = append(readers, r)
+	}
+	return products, readers
+}
+
+// stageFile starts staging the rows of a file read with the given layouts.
+func (is *ImportService) stageFile(format string, invoiceLayout, productLayout *sheetLayout) (*importFile, *repository.ImportStaging, error) {
+	staging, err := repository.StartImportStaging(is.DB, is.instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	file := &importFile{db: is.DB, format: format, id: staging.ID, invoiceLayout: invoiceLayout, productLayout: productLayout}
+	return file, staging, nil
+}
+
+// finishStaging writes the rows staged, or drops them if staging the file failed.
+func finishStaging(staging *repository.ImportStaging, err error) error {
+	if err != nil {
+		staging.Abort()
+		return err
+	}
+	return staging.Finish()
+}
+
+// close deletes the staged rows of the file once its import has ended.
+func (file *importFile) close() {
+	if err := repository.DeleteImportStaging(file.db, file.id); err != nil {
+		log.Printf("Import: failed to delete the rows staged by import %d: %v", file.id, err)
+	}
+}
+
+// stageProducts stages every row of a product table as it is read. The rows that can't be linked to
+// an invoice are recorded as errors instead: empty rows, and rows without an invoice number.
+// Cancelling ctx stops staging before the next row, leaving the import to report the cancellation.
+func (file *importFile) stageProducts(ctx context.Context, staging *repository.ImportStaging, products *importTable, limit int) error {
+	for ctx.Err() == nil {
+		r, ok := products.next()
+		if !ok {
+			break
+		}
+		switch {
+		case isEmptyRow(r.cells):
+			r.errorAt("", models.ImportErrorEmptyRow, "row is empty")
+		case r.invoiceNo == "":
+			r.errorAt("invoice_no", models.ImportErrorRequired, "invoice no is required to link the product to its invoice")
+		default:
+			if err := staging.Add(models.ImportStagedProducts, stagedRow(r)); err != nil {
+				return err
+			}
+			continue
+		}
+		file.unlinked++
+		if len(file.errors) < limit {
+			file.errors = append(file.errors, r.errors...)
+		}
+	}
+	if importErr := products.readError(); importErr != nil {
+		return &ImportFileError{Errors: []models.ImportError{*importErr}}
+	}
+	return nil
+}
+
+// stageInvoices stages every row of an invoice table as it is read. An error reading the rows is kept
+// for the import to report after the rows read before it. Cancelling ctx stops staging before the next row.
+func (file *importFile) stageInvoices(ctx context.Context, staging *repository.ImportStaging, invoices *importTable) error {
+	for ctx.Err() == nil {
+		r, ok := invoices.next()
+		if !ok {
+			break
+		}
+		if err := staging.Add(models.ImportStagedInvoices, stagedRow(r)); err != nil {
+			return err
+		}
+	}
+	file.readErr = invoices.readError()
+	return nil
+}
+
+// stagedRow returns a row read from a table as it is staged.
+func stagedRow(r *rowReader) models.ImportStagedRow {
+	return models.ImportStagedRow{Row: r.row, InvoiceNo: r.invoiceNo, Cells: r.cells, Raw: r.raw}
+}
+
+// next returns the next staged invoice row, reading the next page when the current one is done.
+// It returns false at the end of the rows, or when reading a page fails, which err then holds.
+func (file *importFile) next() (*rowReader, bool) {
+	if len(file.page) == 0 && !file.done && file.err == nil {
+		file.err = file.readPage()
+	}
+	if len(file.page) == 0 {
+		return nil, false
+	}
+	r := file.page[0]
+	file.page = file.page[1:]
+	return r, true
+}
+
+// readPage reads the next page of staged invoice rows, the product rows of their invoice numbers,
+// the rows each of these numbers is on and which of them are taken.
+func (file *importFile) readPage() error {
+	staged, err := repository.GetStagedRows(file.db, file.id, models.ImportStagedInvoices, file.last, importPageSize)
+	if err != nil {
+		return err
+	}
+	if len(staged) == 0 {
+		file.done = true
+		return nil
+	}
+	var invoiceNos []string
+	for _, row := range staged {
+		r := newRowReader(file.invoiceLayout, row.Cells, row.Raw, row.Row)
+		file.page = append(file.page, r)
+		if r.invoiceNo != "" {
+			invoiceNos = append(invoiceNos, r.invoiceNo)
+		}
+		file.last = row.Row
+	}
+
+	products, err := repository.GetStagedProducts(file.db, file.id, invoiceNos)
+	if err != nil {
+		return err
+	}
+	file.products = productIndex{}
+	for _, row := range products {
+		file.products[row.InvoiceNo] = append(file.products[row.InvoiceNo], newRowReader(file.productLayout, row.Cells, row.Raw, row.Row))
+	}
+	if file.numbers, err = repository.GetStagedInvoiceRows(file.db, file.id, invoiceNos); err != nil {
+		return err
+	}
+	file.existing, err = repository.GetExistingInvoiceNumbers(file.db, invoiceNos)
+	return err
+}
+
+// productErrors returns the errors of the product rows no invoice row takes, in row order and up to
+// limit: the rows that can't be linked, and the orphan rows whose invoice number isn't in the invoice
+// sheet. It also returns how many such rows there are in all.
+func (file *importFile) productErrors(limit int) ([]models.ImportError, int, error) {
+	orphans, total, err := repository.GetStagedOrphans(file.db, file.id, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	errs := append([]models.ImportError{}, file.errors...)
+	for _, orphan := range orphans {
+		r := newRowReader(file.productLayout, orphan.Cells, orphan.Raw, orphan.Row)
+		r.errorAt("invoice_no", models.ImportErrorOrphanProduct, fmt.Sprintf("invoice %s is not in the invoice sheet", r.invoiceNo))
+		errs = append(errs, r.errors...)
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Row < errs[j].Row })
+	if len(errs) > limit {
+		errs = errs[:limit]
+	}
+	return errs, file.unlinked + total, nil
+}
+
+// checkInvoiceRow records the problems of an invoice row that no insert should be attempted for:
+// an empty row, an invoice number on several rows, or an invoice without product rows.
+func (file *importFile) checkInvoiceRow(r *rowReader) {
+	switch {
+	case isEmptyRow(r.cells):
+		r.errorAt("", models.ImportErrorEmptyRow, "row is empty")
+	case r.invoiceNo == "":
+		// Reported as required when the row is parsed
+	case len(file.numbers[r.invoiceNo]) > 1:
+		rows := make([]string, len(file.numbers[r.invoiceNo]))
+		for i, row := range file.numbers[r.invoiceNo] {
+			rows[i] = strconv.Itoa(row)
+		}
+		r.errorAt("invoice_no", models.ImportErrorDuplicateInFile,
+			fmt.Sprintf("invoice %s is on rows %s of the sheet", r.invoiceNo, strings.Join(rows, ", ")))
+	case len(file.products[r.invoiceNo]) == 0:
+		r.errorAt("", models.ImportErrorNoProducts, fmt.Sprintf("invoice %s has no product rows", r.invoiceNo))
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/utils"
+)
+
+// ProcessCSVFiles processes an invoice CSV file and a product CSV file laid out like the sheets of
+// the XLSX import, with the same options, validation and errors. Errors name the file as the sheet.
+func (is *ImportService) ProcessCSVFiles(ctx context.Context, invoicesName string, invoicesFile io.Reader, productsName string, productsFile io.Reader, options models.ImportRequest) (*models.ImportResult, error) {
+	format, err := is.loadFormat(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map the columns of both files before processing any row
+	invoices, errs := openTable(invoiceSheet, format, invoicesName, newCSVRows(invoicesFile))
+	products, productErrs := openTable(productSheet, format, productsName, newCSVRows(productsFile))
+	if errs = append(errs, productErrs...); len(errs) > 0 {
+		return nil, &ImportFileError{Errors: errs}
+	}
+
+	loaded, staging, err := is.stageFile(models.ImportFormatCSV, invoices.layout, products.layout)
+	if err != nil {
+		return nil, err
+	}
+	defer loaded.close()
+	err = loaded.stageProducts(ctx, staging, products, utils.ImportResultLimit())
+	if err == nil {
+		err = loaded.stageInvoices(ctx, staging, invoices)
+	}
+	if err := finishStaging(staging, err); err != nil {
+		return nil, err
+	}
+	return is.importRows(ctx, loaded, options)
+}
+
+// ProcessCSVFile processes a single CSV file with one row per product line. Each row holds the invoice
+// columns as well as the product columns; the rows of an invoice share its invoice number, and the
+// invoice columns are read from the first of them.
+func (is *ImportService) ProcessCSVFile(ctx context.Context, name string, file io.Reader, options models.ImportRequest) (*models.ImportResult, error) {
+	format, err := is.loadFormat(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same rows are read once as invoices and once as products
+	table, errs := openTable(invoiceSheet, format, name, newCSVRows(file))
+	if table == nil {
+		return nil, &ImportFileError{Errors: errs}
+	}
+	productLayout, productErrs := newSheetLayout(productSheet, format, name, table.layout.header)
+	if errs = append(errs, productErrs...); len(errs) > 0 {
+		return nil, &ImportFileError{Errors: dedupeImportErrors(errs)}
+	}
+
+	// The rows of an invoice may be anywhere in the file, so every row is staged before importing: as a
+	// product row, and as an invoice row of which only the first of each invoice number is kept. So the
+	// rows of an invoice are never duplicates.
+	loaded, staging, err := is.stageFile(models.ImportFormatCSV, table.layout, productLayout)
+	if err != nil {
+		return nil, err
+	}
+	defer loaded.close()
+	err = stageCSVRows(ctx, staging, table, productLayout)
+	if err := finishStaging(staging, err); err != nil {
+		return nil, err
+	}
+	if err := repository.DeleteStagedRepeatedInvoices(is.DB, loaded.id); err != nil {
+		return nil, err
+	}
+	return is.importRows(ctx, loaded, options)
+}
+
+// stageCSVRows stages each row of a single CSV file as an invoice row, and as a product row if it has
+// an invoice number. The whole file is rejected if it can't be read to the end. Cancelling ctx stops
+// staging before the next row.
+func stageCSVRows(ctx context.Context, staging *repository.ImportStaging, table *importTable, productLayout *sheetLayout) error {
+	for ctx.Err() == nil {
+		r, ok := table.next()
+		if !ok {
+			break
+		}
+		if err := staging.Add(models.ImportStagedInvoices, stagedRow(r)); err != nil {
+			return err
+		}
+		product := newRowReader(productLayout, r.cells, r.raw, r.row)
+		if product.invoiceNo != "" && !isEmptyRow(product.cells) {
+			if err := staging.Add(models.ImportStagedProducts, stagedRow(product)); err != nil {
+				return err
+			}
+		}
+	}
+	if importErr := table.readError(); importErr != nil {
+		return &ImportFileError{Errors: []models.ImportError{*importErr}}
+	}
+	return nil
+}
+
+// dedupeImportErrors drops repeated errors, such as the missing invoice no column that both the invoice
+// and the product columns of a single CSV file report.
+func dedupeImportErrors(errs []models.ImportError) []models.ImportError {
+	seen := map[string]bool{}
+	var unique []models.ImportError
+	for _, importErr := range errs {
+		key := fmt.Sprintf("%+v", importErr)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, importErr)
+		}
+	}
+	return unique
+}
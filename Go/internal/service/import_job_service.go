@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/utils"
+)
+
+// ErrImportJobUnfinished is returned when asking for the error report of a job that has no result.
+var ErrImportJobUnfinished = errors.New("import job has no result to report")
+
+// importJobPollInterval is how often idle workers look for queued jobs nobody woke them for,
+// such as jobs queued before a restart.
+const importJobPollInterval = 5 * time.Second
+
+// importJobCancelInterval is how often a running job checks whether it has been asked to cancel.
+const importJobCancelInterval = time.Second
+
+// importInstanceHeartbeat is how often a server records that it is running, and cleans up after the
+// servers that stopped.
+const importInstanceHeartbeat = 10 * time.Second
+
+// importInstanceStaleAfter is how long a server can go without recording that it is running before
+// the others take it for stopped, failing the jobs it was running and deleting the rows it staged.
+const importInstanceStaleAfter = time.Minute
+
+// ImportJobService defines the service layer for import jobs: uploads stored in the database and
+// imported in the background by a pool of workers.
+type ImportJobService struct {
+	DB            *sql.DB
+	ImportService *ImportService
+	instance      string // INSTANCE_ID of this server
+	wake          chan struct{}
+}
+
+// NewImportJobService creates a new ImportJobService instance
+func NewImportJobService(db *sql.DB, importService *ImportService) *ImportJobService {
+	return &ImportJobService{DB: db, ImportService: importService, instance: utils.InstanceID(), wake: make(chan struct{}, 1)}
+}
+
+// Start cleans up after the previous run of this server and after the servers that stopped, records
+// that this server is running, and starts the workers. The jobs and staged rows of the other servers
+// sharing the database are left alone while they keep recording that they are running.
+func (js *ImportJobService) Start(workers int) {
+	js.cleanUp(true)
+	js.beat()
+	go js.heartbeat()
+	for i := 0; i < workers; i++ {
+		go js.work()
+	}
+}
+
+// heartbeat records every importInstanceHeartbeat that this server is running, and cleans up after
+// the servers that stopped meanwhile.
+func (js *ImportJobService) heartbeat() {
+	ticker := time.NewTicker(importInstanceHeartbeat)
+	defer ticker.Stop()
+	for range ticker.C {
+		js.beat()
+		js.cleanUp(false)
+	}
+}
+
+// beat records that this server is running.
+func (js *ImportJobService) beat() {
+	if err := repository.BeatImportInstance(js.DB, js.instance); err != nil {
+		log.Printf("Import jobs: failed to record that %s is running: %v", js.instance, err)
+	}
+}
+
+// cleanUp marks the jobs the stopped servers left running as failed and deletes the rows their
+// unfinished imports staged. Once restarted, this server cleans up after its previous run as well.
+func (js *ImportJobService) cleanUp(restarted bool) {
+	stopped, err := repository.GetStoppedImportInstances(js.DB, importInstanceStaleAfter)
+	if err != nil {
+		log.Printf("Import jobs: failed to look for stopped servers: %v", err)
+		return
+	}
+	if restarted {
+		stopped = append(stopped, js.instance)
+	}
+	if err := repository.FailInterruptedImportJobs(js.DB, stopped); err != nil {
+		log.Printf("Import jobs: failed to clear interrupted jobs: %v", err)
+		return
+	}
+	if err := repository.ClearImportStaging(js.DB, stopped); err != nil {
+		log.Printf("Import jobs: failed to clear the rows staged by unfinished imports: %v", err)
+		return
+	}
+	if err := repository.DeleteImportInstances(js.DB, stopped, importInstanceStaleAfter); err != nil {
+		log.Printf("Import jobs: failed to forget stopped servers: %v", err)
+	}
+}
+
+// Submit stores an upload as a queued import job and wakes a worker to process it.
+func (js *ImportJobService) Submit(uploads []ImportUpload, options models.ImportRequest) (*models.ImportJob, error) {
+	// An unknown profile is reported now rather than as a failed job
+	if _, err := js.ImportService.loadFormat(options); err != nil {
+		return nil, err
+	}
+
+	job := &models.ImportJob{Format: uploads[0].Format, DryRun: options.DryRun, Atomic: options.Atomic, Profile: options.Profile,
+		OnConflict: onConflict(options)}
+	files := make([]models.ImportJobFile, len(uploads))
+	for i, upload := range uploads {
+		content, err := io.ReadAll(upload)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = models.ImportJobFile{Field: upload.Field, Name: upload.Name, Content: content}
+	}
+	if err := repository.CreateImportJob(js.DB, job, files); err != nil {
+		return nil, err
+	}
+	js.notify()
+	return job, nil
+}
+
+// GetImportJob retrieves an import job with its progress, or its result once finished.
+func (js *ImportJobService) GetImportJob(id int) (models.ImportJob, error) {
+	return repository.GetImportJob(js.DB, id)
+}
+
+// CancelImportJob cancels a queued job, or asks a running job to stop. The running job notices within
+// importJobCancelInterval, and stops before its next row.
+func (js *ImportJobService) CancelImportJob(id int) (models.ImportJob, error) {
+	return repository.CancelImportJob(js.DB, id)
+}
+
+// ImportJobReport returns the error report of a finished job, and a name for it after the uploaded file.
+// It returns ErrImportJobUnfinished if the job has no result yet, or never will.
+func (js *ImportJobService) ImportJobReport(id int) ([]byte, string, error) {
+	job, err := repository.GetImportJob(js.DB, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Result == nil {
+		return nil, "", ErrImportJobUnfinished
+	}
+	files, err := repository.GetImportJobFiles(js.DB, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) != 1 {
+		return nil, "", ErrImportReportUnsupported
+	}
+	options := models.ImportRequest{DryRun: job.DryRun, Atomic: job.Atomic, Profile: job.Profile, OnConflict: job.OnConflict}
+	report, err := js.ImportService.ErrorReport(bytes.NewReader(files[0].Content), job.Result, options)
+	return report, ReportFileName(files[0].Name), err
+}
+
+// notify wakes an idle worker, if none is already being woken.
+func (js *ImportJobService) notify() {
+	select {
+	case js.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work claims and runs queued jobs until the server stops.
+func (js *ImportJobService) work() {
+	for {
+		job, err := repository.ClaimImportJob(js.DB, js.instance)
+		if err == nil {
+			js.notify() // Another worker may take the next queued job meanwhile
+			js.run(job)
+			continue
+		}
+		if err != sql.ErrNoRows {
+			log.Printf("Import jobs: failed to claim a job: %v", err)
+		}
+		select {
+		case <-js.wake:
+		case <-time.After(importJobPollInterval):
+		}
+	}
+}
+
+// run imports the files of a claimed job and records its outcome. The progress of the import is saved
+// as it goes. A cancel request is watched for meanwhile, and cancels the context of the import, which
+// checks it before every row.
+func (js *ImportJobService) run(job models.ImportJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go js.watchCancel(ctx, job.ID, cancel)
+	ctx = WithImportProgress(ctx, func(processed, rejected int) {
+		cancelRequested, err := repository.UpdateImportJobProgress(js.DB, job.ID, processed, rejected)
+		if err != nil {
+			log.Printf("Import jobs: failed to save the progress of job %d: %v", job.ID, err)
+		}
+		if cancelRequested {
+			cancel()
+		}
+	})
+
+	defer func() {
+		// A crash in one job must not take the server down with it
+		if r := recover(); r != nil {
+			job.Status, job.Error = models.ImportJobFailed, fmt.Sprintf("the import crashed: %v", r)
+			js.finish(&job)
+		}
+	}()
+
+	result, err := js.importFiles(ctx, job)
+	var fileErr *ImportFileError
+	switch {
+	case errors.As(err, &fileErr):
+		job.Status, job.Error, job.Errors = models.ImportJobFailed, fileErr.Error(), fileErr.Errors
+	case err != nil:
+		job.Status, job.Error = models.ImportJobFailed, err.Error()
+	default:
+		job.Status = models.ImportJobCompleted
+		if result.Cancelled {
+			job.Status = models.ImportJobCancelled
+		}
+		job.Processed, job.Failed, job.Errors, job.Result = result.Processed, result.Rejected, result.Errors, result
+	}
+	js.finish(&job)
+}
+
+// watchCancel checks every importJobCancelInterval whether a running job has been asked to cancel,
+// calling cancel when it has, until ctx is done.
+func (js *ImportJobService) watchCancel(ctx context.Context, id int, cancel context.CancelFunc) {
+	ticker := time.NewTicker(importJobCancelInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelRequested, err := repository.ImportJobCancelRequested(js.DB, id)
+			if err != nil {
+				log.Printf("Import jobs: failed to check whether job %d was cancelled: %v", id, err)
+			}
+			if cancelRequested {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// importFiles imports the files stored for a job with the job's options.
+func (js *ImportJobService) importFiles(ctx context.Context, job models.ImportJob) (*models.ImportResult, error) {
+	files, err := repository.GetImportJobFiles(js.DB, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	uploads := make([]ImportUpload, len(files))
+	for i, file := range files {
+		uploads[i] = ImportUpload{Field: file.Field, Name: file.Name, Format: job.Format, Reader: bytes.NewReader(file.Content)}
+	}
+	options := models.ImportRequest{DryRun: job.DryRun, Atomic: job.Atomic, Profile: job.Profile, OnConflict: job.OnConflict}
+	return js.ImportService.Import(ctx, uploads, options)
+}
+
+// finish records the outcome of a job, logging a failure since there is no request to report it to.
+func (js *ImportJobService) finish(job *models.ImportJob) {
+	if err := repository.FinishImportJob(js.DB, job); err != nil {
+		log.Printf("Import jobs: failed to record the outcome of job %d: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// ImportProfileService defines the service layer for import profile operations
+type ImportProfileService struct {
+	DB *sql.DB
+}
+
+// NewImportProfileService creates a new ImportProfileService instance
+func NewImportProfileService(db *sql.DB) *ImportProfileService {
+	return &ImportProfileService{DB: db}
+}
+
+// GetImportProfiles retrieves all import profiles
+func (ps *ImportProfileService) GetImportProfiles() ([]models.ImportProfile, error) {
+	return repository.GetImportProfiles(ps.DB)
+}
+
+// GetImportProfile retrieves an import profile by its name
+func (ps *ImportProfileService) GetImportProfile(name string) (models.ImportProfile, error) {
+	return repository.GetImportProfile(ps.DB, name)
+}
+
+// CreateImportProfile creates a new import profile
+func (ps *ImportProfileService) CreateImportProfile(profile *models.ImportProfile) error {
+	return repository.CreateImportProfile(ps.DB, profile)
+}
+
+// UpdateImportProfile updates an existing import profile
+func (ps *ImportProfileService) UpdateImportProfile(profile *models.ImportProfile) error {
+	return repository.UpdateImportProfile(ps.DB, profile)
+}
+
+// DeleteImportProfile deletes an import profile by its name
+func (ps *ImportProfileService) DeleteImportProfile(name string) error {
+	return repository.DeleteImportProfile(ps.DB, name)
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// ErrImportReportUnsupported is returned when asking for the error report of an import that wasn't a workbook.
+	ErrImportReportUnsupported = errors.New("error reports are only available for XLSX workbooks")
+	// ErrNothingToReport is returned when asking for the error report of an import that imported every row.
+	ErrNothingToReport = errors.New("every row was imported, so there is nothing to report")
+)
+
+// reportErrorsHeader is the header of the column an error report explains each failure in.
+const reportErrorsHeader = "errors"
+
+// reportFill highlights the rows of an error report that failed to import.
+var reportFill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}
+
+// HasRowsToReport reports whether an import left rows out that an error report would list.
+func HasRowsToReport(result *models.ImportResult) bool {
+	return result.Rejected > 0 || result.RolledBack > 0 || result.Cancelled
+}
+
+// ErrorReport returns a copy of an imported workbook to fix and upload again: the rows that weren't
+// imported, with the failed rows highlighted and an errors column explaining each failure. The rows
+// imported, or that a dry run would import, are removed along with their products and the empty rows.
+// Which invoice rows weren't imported is read from the summary of the result, as its rows may not all
+// be listed; rejected rows whose errors the result leaves out get a note saying so.
+func (is *ImportService) ErrorReport(workbook io.Reader, result *models.ImportResult, options models.ImportRequest) ([]byte, error) {
+	if result.Format != models.ImportFormatXLSX {
+		return nil, ErrImportReportUnsupported
+	}
+	if !HasRowsToReport(result) {
+		return nil, ErrNothingToReport
+	}
+
+	f, err := excelize.OpenReader(workbook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX file: %w", err)
+	}
+	defer f.Close()
+	format, err := is.loadFormat(options)
+	if err != nil {
+		return nil, err
+	}
+	invoiceName, ok := findSheet(f, invoiceSheet, format.profile.InvoiceSheet)
+	if !ok {
+		return nil, ErrNothingToReport // The import rejected the file before reading any row
+	}
+	productName, ok := findSheet(f, productSheet, format.profile.ProductSheet)
+	if !ok {
+		return nil, ErrNothingToReport
+	}
+
+	// Explain each row with errors, and why valid rows weren't imported either
+	notes := map[string]map[int][]string{invoiceName: {}, productName: {}}
+	for _, importErr := range result.Errors {
+		if notes[importErr.Sheet] != nil {
+			notes[importErr.Sheet][importErr.Row] = append(notes[importErr.Sheet][importErr.Row], reportNote(importErr))
+		}
+	}
+	rejected := parseRowRanges(result.RejectedRows)
+	rolledBack := result.Mode == models.ImportModeAtomic && (result.Rejected > 0 || result.Cancelled)
+
+	w := &reportWriter{f: f, highlights: map[int]int{}}
+	var layout *sheetLayout
+	imported := map[string]bool{} // Invoices whose product rows are done with
+	err = w.rewriteSheet(invoiceName, func(row int, cells []string) bool {
+		if row == 1 {
+			layout, _ = newSheetLayout(invoiceSheet, format, invoiceName, cells)
+			return true
+		}
+		if isEmptyRow(cells) {
+			return false
+		}
+		rowNotes := notes[invoiceName]
+		switch {
+		case rejected.contains(row):
+			if len(rowNotes[row]) == 0 && result.Truncated {
+				rowNotes[row] = []string{"not imported: rejected, with errors past the ones the import result lists"}
+			} else if len(rowNotes[row]) == 0 {
+				rowNotes[row] = []string{"not imported because of the errors of its product rows"}
+			}
+		case result.Cancelled && row > result.LastRow:
+			rowNotes[row] = []string{"not imported: the import was cancelled before this row"}
+		case rolledBack:
+			rowNotes[row] = []string{"not imported: the atomic import was rolled back because other rows were rejected"}
+		default:
+			// Imported, or an existing invoice left as it is
+			if invoiceNo := newRowReader(layout, cells, nil, row).invoiceNo; invoiceNo != "" {
+				imported[invoiceNo] = true
+			}
+			return false
+		}
+		return true
+	}, notes[invoiceName])
+	if err != nil {
+		return nil, err
+	}
+	err = w.rewriteSheet(productName, func(row int, cells []string) bool {
+		if row == 1 {
+			layout, _ = newSheetLayout(productSheet, format, productName, cells)
+			return true
+		}
+		return !isEmptyRow(cells) && !imported[newRowReader(layout, cells, nil, row).invoiceNo]
+	}, notes[productName])
+	if err != nil {
+		return nil, err
+	}
+	if index, err := f.GetSheetIndex(invoiceName); err == nil {
+		f.SetActiveSheet(index)
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReportFileName names the error report of an uploaded file, e.g. invoices-errors.xlsx for invoices.xlsx.
+func ReportFileName(upload string) string {
+	return strings.TrimSuffix(upload, filepath.Ext(upload)) + "-errors.xlsx"
+}
+
+// reportNote explains an import error in the errors column of its row, naming its column.
+func reportNote(importErr models.ImportError) string {
+	if importErr.Column == "" {
+		return importErr.Message
+	}
+	return fmt.Sprintf("%s (%s): %s", importErr.Column, importErr.Header, importErr.Message)
+}
+
+// reportWriter rewrites the sheets of an error report.
+type reportWriter struct {
+	f          *excelize.File
+	highlights map[int]int // Highlighted copy of each cell style
+}
+
+// rewriteSheet replaces a sheet by a copy of the rows keep keeps, in order, with the notes of each row
+// in the errors column and the rows with notes highlighted. keep is given each row as displayed, the
+// header first, and may add notes to the row. The sheet is streamed in and the copy streamed out, so
+// neither is held in memory as rows. Cells keep their type and style; formulas are replaced by their
+// values, as the rows they refer to may be gone.
+func (w *reportWriter) rewriteSheet(name string, keep func(row int, cells []string) bool, notes map[int][]string) error {
+	source, err := openSheetRows(w.f, name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	var header []string
+	if source.Next() {
+		header, _ = source.Row()
+	}
+	if err := source.Err(); err != nil {
+		return err
+	}
+	keep(1, header)
+	errorsColumn := len(header)
+	for i, value := range header {
+		if utils.NormalizeHeader(value) == reportErrorsHeader {
+			errorsColumn = i // A report uploaded again keeps a single errors column
+		}
+	}
+
+	sheets := w.f.GetSheetList()
+	position := 0
+	for i, sheet := range sheets {
+		if sheet == name {
+			position = i
+		}
+	}
+	copyName := "report " + strconv.Itoa(position)
+	if _, err := w.f.NewSheet(copyName); err != nil {
+		return err
+	}
+	sw, err := w.f.NewStreamWriter(copyName)
+	if err != nil {
+		return err
+	}
+	for col := 1; col <= errorsColumn; col++ {
+		colName, _ := excelize.ColumnNumberToName(col)
+		if width, err := w.f.GetColWidth(name, colName); err == nil {
+			sw.SetColWidth(col, col, width)
+		}
+	}
+	sw.SetColWidth(errorsColumn+1, errorsColumn+1, 60)
+
+	_, raw := source.Row()
+	if err := w.copyRow(sw, name, 1, 1, raw, errorsColumn, nil); err != nil {
+		return err
+	}
+	copied := 1
+	for row := 2; source.Next(); row++ {
+		cells, raw := source.Row()
+		if !keep(row, cells) {
+			continue
+		}
+		copied++
+		if err := w.copyRow(sw, name, row, copied, raw, errorsColumn, notes[row]); err != nil {
+			return err
+		}
+	}
+	if err := source.Err(); err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	source.Close()
+
+	// Put the copy in place of the sheet
+	if err := w.f.DeleteSheet(name); err != nil {
+		return err
+	}
+	if err := w.f.SetSheetName(copyName, name); err != nil {
+		return err
+	}
+	if position+1 < len(sheets) {
+		return w.f.MoveSheet(name, sheets[position+1])
+	}
+	return nil
+}
+
+// copyRow writes a row of a sheet as a row of the copy, with its notes in the errors column and
+// highlighted if it has any. The header row gets the header of the errors column instead.
+func (w *reportWriter) copyRow(sw *excelize.StreamWriter, name string, row, to int, values []string, errorsColumn int, notes []string) error {
+	highlighted := len(notes) > 0
+	copied := make([]any, max(errorsColumn+1, len(values)))
+	for col := range copied {
+		if col == errorsColumn || (!highlighted && cellValue(values, col) == "") {
+			continue
+		}
+		from, _ := excelize.CoordinatesToCellName(col+1, row)
+		cell, err := w.copyCell(name, from, cellValue(values, col), highlighted)
+		if err != nil {
+			return err
+		}
+		copied[col] = cell
+	}
+
+	switch {
+	case row == 1:
+		style, _ := w.f.GetCellStyle(name, "A1")
+		copied[errorsColumn] = excelize.Cell{StyleID: style, Value: reportErrorsHeader}
+	case highlighted:
+		style, err := w.highlight(0)
+		if err != nil {
+			return err
+		}
+		copied[errorsColumn] = excelize.Cell{StyleID: style, Value: strings.Join(notes, "; ")}
+	}
+	cell, _ := excelize.CoordinatesToCellName(1, to)
+	return sw.SetRow(cell, copied)
+}
+
+// copyCell returns a copy of the value of a cell with its type and style, highlighted if asked.
+func (w *reportWriter) copyCell(sheet, from, value string, highlighted bool) (excelize.Cell, error) {
+	cellType, err := w.f.GetCellType(sheet, from)
+	if err != nil {
+		return excelize.Cell{}, err
+	}
+	cell := excelize.Cell{}
+	switch {
+	case value == "":
+	case cellType == excelize.CellTypeBool:
+		cell.Value = value == "1" || strings.EqualFold(value, "true")
+	case cellType == excelize.CellTypeUnset || cellType == excelize.CellTypeNumber:
+		if n, parseErr := strconv.ParseFloat(value, 64); parseErr == nil {
+			cell.Value = n
+		} else {
+			cell.Value = value
+		}
+	default:
+		cell.Value = value
+	}
+
+	if cell.StyleID, err = w.f.GetCellStyle(sheet, from); err != nil {
+		return excelize.Cell{}, err
+	}
+	if highlighted {
+		if cell.StyleID, err = w.highlight(cell.StyleID); err != nil {
+			return excelize.Cell{}, err
+		}
+	}
+	return cell, nil
+}
+
+// highlight returns a copy of a cell style with the fill of the failed rows.
+func (w *reportWriter) highlight(style int) (int, error) {
+	if highlighted, ok := w.highlights[style]; ok {
+		return highlighted, nil
+	}
+	s, err := w.f.GetStyle(style)
+	if err != nil {
+		return 0, err
+	}
+	s.Fill = reportFill
+	highlighted, err := w.f.NewStyle(s)
+	if err != nil {
+		return 0, err
+	}
+	w.highlights[style] = highlighted
+	return highlighted, nil
+}
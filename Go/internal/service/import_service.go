@@ -1,117 +1,660 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
-	"time"
+	"strings"
 	"widatech-technical-challenge/internal/models"
 	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/utils"
 
 	"github.com/xuri/excelize/v2"
 )
 
+// ErrImportProfileNotFound is returned when an import names a profile that doesn't exist.
+var ErrImportProfileNotFound = errors.New("import profile not found")
+
 // ImportService defines the service layer for importing invoices and products
 type ImportService struct {
-	DB *sql.DB
+	DB       *sql.DB
+	instance string // INSTANCE_ID of this server, which owns the rows its imports stage
 }
 
 // NewImportService creates a new ImportService instance
 func NewImportService(db *sql.DB) *ImportService {
-	return &ImportService{DB: db}
+	return &ImportService{DB: db, instance: utils.InstanceID()}
+}
+
+// importProgressInterval is how many invoice rows an import processes between progress reports.
+const importProgressInterval = 100
+
+// ImportProgress is told how many invoice rows an import has processed, and rejected, so far.
+type ImportProgress func(processed, rejected int)
+
+type importProgressKey struct{}
+
+// WithImportProgress returns a context whose imports report their progress to progress as they go.
+func WithImportProgress(ctx context.Context, progress ImportProgress) context.Context {
+	return context.WithValue(ctx, importProgressKey{}, progress)
+}
+
+// ImportUpload is a file uploaded for import, with the format sniffed from its content.
+type ImportUpload struct {
+	Field  string // Form field of the upload: file, or invoices and products for one CSV file per sheet
+	Name   string
+	Format string
+	io.Reader
 }
 
-// ProcessXLSXFile processes and validates the uploaded XLSX file
-func (is *ImportService) ProcessXLSXFile(file io.Reader) ([]map[string]string, error) {
+// Import processes the files of an upload: a workbook or a single CSV file uploaded as "file",
+// or an invoice and a product CSV file uploaded as "invoices" and "products".
+func (is *ImportService) Import(ctx context.Context, uploads []ImportUpload, options models.ImportRequest) (*models.ImportResult, error) {
+	files := map[string]ImportUpload{}
+	for _, upload := range uploads {
+		files[upload.Field] = upload
+	}
+	if invoices, ok := files["invoices"]; ok {
+		products := files["products"]
+		return is.ProcessCSVFiles(ctx, invoices.Name, invoices, products.Name, products, options)
+	}
+	file := files["file"]
+	if file.Format == models.ImportFormatCSV {
+		return is.ProcessCSVFile(ctx, file.Name, file, options)
+	}
+	return is.ProcessXLSXFile(ctx, file, options)
+}
+
+// ProcessXLSXFile processes and validates the uploaded XLSX file, creating the valid invoices and
+// reporting the rejected rows. An atomic import creates them only if no row is rejected.
+// On a dry run the whole file is checked the same way but nothing is kept.
+// The sheets are streamed into staged rows in the database, which the import then reads back a page of
+// invoice rows at a time with their product rows, so neither sheet is held in memory.
+// Cancelling ctx stops the import after the current row, even while the sheets are being staged.
+func (is *ImportService) ProcessXLSXFile(ctx context.Context, file io.Reader, options models.ImportRequest) (*models.ImportResult, error) {
+	// A zip archive that isn't a workbook, or is cut short, is a bad upload rather than a server error
 	f, err := excelize.OpenReader(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse XLSX file: %w", err)
+		return nil, &ImportFileError{Errors: []models.ImportError{{
+			Code:    models.ImportErrorInvalidXLSX,
+			Message: fmt.Sprintf("the file can't be read as an XLSX workbook: %v", err),
+		}}}
 	}
+	defer f.Close()
 
-	var errors []map[string]string
+	format, err := is.loadFormat(options)
+	if err != nil {
+		return nil, err
+	}
+	if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		format.date1904 = *props.Date1904
+	}
 
-	// Process the "product_sold" sheet
-	productRows, err := f.GetRows("product sold")
+	// Find both sheets and map their columns before processing any row
+	invoices, errs := openSheet(f, invoiceSheet, format, format.profile.InvoiceSheet)
+	if invoices != nil {
+		defer invoices.source.Close()
+	}
+	products, productErrs := openSheet(f, productSheet, format, format.profile.ProductSheet)
+	if products != nil {
+		defer products.source.Close()
+	}
+	if errs = append(errs, productErrs...); len(errs) > 0 {
+		return nil, &ImportFileError{Errors: errs}
+	}
+
+	loaded, staging, err := is.stageFile(models.ImportFormatXLSX, invoices.layout, products.layout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read product_sold sheet: %w", err)
+		return nil, err
+	}
+	defer loaded.close()
+	err = loaded.stageProducts(ctx, staging, products, utils.ImportResultLimit())
+	if err == nil {
+		err = loaded.stageInvoices(ctx, staging, invoices)
+	}
+	if err := finishStaging(staging, err); err != nil {
+		return nil, err
+	}
+	return is.importRows(ctx, loaded, options)
+}
+
+// loadFormat reads how the values of the file are written from the import profile named in the options,
+// or returns the defaults without one.
+func (is *ImportService) loadFormat(options models.ImportRequest) (importFormat, error) {
+	var profile models.ImportProfile
+	if options.Profile != "" {
+		var err error
+		profile, err = repository.GetImportProfile(is.DB, options.Profile)
+		if err == sql.ErrNoRows {
+			return importFormat{}, fmt.Errorf("%w: %s", ErrImportProfileNotFound, options.Profile)
+		}
+		if err != nil {
+			return importFormat{}, err
+		}
 	}
+	return newImportFormat(profile)
+}
 
-	// Process the "invoice" sheet
-	invoiceRows, err := f.GetRows("invoice")
+// openSheet finds an import sheet in the workbook, under the profile's name for it if set,
+// and starts reading its rows after mapping the columns of its header.
+func openSheet(f *excelize.File, sheet importSheet, format importFormat, profileName string) (*importTable, []models.ImportError) {
+	name, ok := findSheet(f, sheet, profileName)
+	if !ok {
+		if profileName != "" {
+			sheet.Name = profileName
+		}
+		return nil, []models.ImportError{{
+			Sheet:   sheet.Name,
+			Code:    models.ImportErrorMissingSheet,
+			Message: fmt.Sprintf("sheet %q is missing", sheet.Name),
+		}}
+	}
+	rows, err := openSheetRows(f, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read invoice sheet: %w", err)
+		return nil, []models.ImportError{{Sheet: name, Code: models.ImportErrorFailed, Message: err.Error()}}
+	}
+	table, errs := openTable(sheet, format, name, rows)
+	if table == nil {
+		rows.Close()
+	}
+	return table, errs
+}
+
+// importRows imports the staged invoice rows of a file with the product rows of their invoice number.
+// The rows that don't fit together are rejected without trying to insert them: empty rows, invoice
+// numbers on several rows, invoices without products and product rows without an invoice.
+// ctx is checked before every row. When it is cancelled, the rows not processed yet are left out: a
+// partial import keeps the invoices created so far, while an atomic import is rolled back as if a row
+// had been rejected.
+func (is *ImportService) importRows(ctx context.Context, file *importFile, options models.ImportRequest) (*models.ImportResult, error) {
+	var err error
+
+	// New invoices are queued and inserted IMPORT_BATCH_SIZE rows at a time, with one statement for the
+	// invoices and one for their products under a savepoint. If that fails, each invoice of the batch is
+	// saved under a savepoint of its own, so the rows at fault are rejected without losing the others.
+	// Invoices whose number is taken are saved one at a time as they come, as on_conflict says.
+	// Atomic imports and dry runs import into one transaction, committed at the end only by an
+	// atomic import without errors. A dry run is rolled back, but the duplicate checks, numbering and
+	// validation behave exactly as in a real import, including between rows of the file.
+	// A partial import commits each batch instead.
+	var tx *sql.Tx
+	var lookups *repository.InvoiceLookups
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	batched := !options.Atomic && !options.DryRun
+	batchSize := utils.ImportBatchSize()
+	var queue []*queuedRow
+
+	result := &importResult{
+		ImportResult: &models.ImportResult{
+			Format:     file.format,
+			Mode:       models.ImportModePartial,
+			OnConflict: onConflict(options),
+			DryRun:     options.DryRun,
+			Rows:       []models.ImportRow{},
+			Errors:     []models.ImportError{},
+			Warnings:   []map[string]string{},
+		},
+		limit: utils.ImportResultLimit(),
+	}
+	// Product rows no invoice row takes are rejected up front, unless the import was cancelled while
+	// the file was staged
+	if ctx.Err() == nil {
+		productErrs, rejected, err := file.productErrors(result.limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Rejected += rejected
+		result.listErrors(productErrs)
+		if len(productErrs) < rejected {
+			result.Truncated = true
+		}
 	}
 
-	for i, row := range invoiceRows {
-		if i == 0 {
-			continue // Skip the header
+	// flush inserts the new invoices queued, lists the outcome of the queued rows in file order and
+	// commits them in a partial import
+	flush := func() error {
+		if tx != nil {
+			insertQueued(tx, queue, result.OnConflict)
 		}
-		invoiceID := row[0]
-		var products []models.Product
+		for _, row := range queue {
+			result.finish(row, options.DryRun)
+		}
+		queue = queue[:0]
+		if batched && tx != nil {
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			tx = nil
+		}
+		return nil
+	}
+
+	progress, _ := ctx.Value(importProgressKey{}).(ImportProgress)
+	reported := 0
+	for {
+		if progress != nil && result.Processed >= reported+importProgressInterval {
+			reported = result.Processed
+			progress(reported, result.Rejected)
+		}
+		if ctx.Err() != nil {
+			result.Cancelled = true
+			break
+		}
+
+		invoiceRow, ok := file.next()
+		if !ok {
+			break
+		}
+		result.LastRow = invoiceRow.row
 
 		// Associate products with the corresponding invoice
-		for j, productRow := range productRows {
-			if j == 0 {
-				continue // Skip the header
+		var lines []models.Product
+		var productReaders []*rowReader
+		if invoiceRow.invoiceNo != "" {
+			lines, productReaders = file.products.lines(invoiceRow.invoiceNo)
+		}
+
+		file.checkInvoiceRow(invoiceRow)
+		row := &queuedRow{
+			record:  models.ImportRow{Row: invoiceRow.row, InvoiceNo: invoiceRow.invoiceNo},
+			readers: append([]*rowReader{invoiceRow}, productReaders...),
+		}
+		if !isEmptyRow(invoiceRow.cells) {
+			row.invoice = parseInvoiceRow(invoiceRow, lines)
+		}
+		if len(collectErrors(row.readers)) == 0 {
+			if tx == nil {
+				if tx, err = is.DB.Begin(); err != nil {
+					return nil, err
+				}
+				if lookups, err = repository.LoadInvoiceLookups(tx); err != nil {
+					return nil, err
+				}
+			}
+			if file.existing[row.invoice.InvoiceNo] {
+				row.action, row.issues, err = saveInvoice(tx, &row.invoice, result.OnConflict)
+			} else {
+				row.issues, err = repository.PrepareInvoice(lookups, &row.invoice)
+				row.insert = err == nil
+			}
+			if err != nil {
+				locateError(err, invoiceRow, productReaders)
+			}
+		}
+		queue = append(queue, row)
+		if len(queue) == batchSize {
+			if err = flush(); err != nil {
+				return nil, err
 			}
-			if productRow[0] == invoiceID {
-				quantity, _ := strconv.Atoi(productRow[2])
-				totalCost, _ := strconv.ParseFloat(productRow[3], 64)
-				totalPrice, _ := strconv.ParseFloat(productRow[4], 64)
-
-				product := models.Product{
-					InvoiceNo:  productRow[0],
-					ItemName:   productRow[1],
-					Quantity:   quantity,
-					TotalCost:  totalCost,
-					TotalPrice: totalPrice,
+		}
+	}
+	if file.err != nil {
+		return nil, file.err
+	}
+	if err = flush(); err != nil {
+		return nil, err
+	}
+
+	// A file that can't be read to the end rejects the rest of it
+	if importErr := file.readErr; importErr != nil && !result.Cancelled {
+		result.reject(models.ImportRow{
+			Row:    importErr.Row,
+			Action: models.ImportActionReject,
+			Errors: []models.ImportError{*importErr},
+		})
+	}
+	result.RejectedRows = result.rejectedRows.String()
+
+	if options.Atomic {
+		result.Mode = models.ImportModeAtomic
+		if result.Rejected > 0 || result.Cancelled {
+			// One rejected row undoes the whole file
+			for i := range result.Rows {
+				if action := result.Rows[i].Action; action == models.ImportActionCreate || action == models.ImportActionUpdate {
+					result.Rows[i].Action = models.ImportActionRollback
 				}
-				products = append(products, product)
 			}
+			result.RolledBack = result.Created + result.Updated
+			result.Created, result.Updated = 0, 0
+			return result.ImportResult, nil
+		}
+	}
+	if tx != nil && !options.DryRun {
+		if err = tx.Commit(); err != nil {
+			return nil, err
 		}
+		tx = nil
+	}
 
-		err := validateAndInsertInvoice(row, products, is)
-		if err != nil {
-			errors = append(errors, map[string]string{
-				"invoice_id": invoiceID,
-				"error":      err.Error(),
-			})
+	return result.ImportResult, nil
+}
+
+// queuedRow is an invoice row whose outcome is listed once the new invoices queued with it are inserted.
+type queuedRow struct {
+	record  models.ImportRow
+	invoice models.Invoice
+	readers []*rowReader // The invoice row, then its product rows
+	action  string
+	issues  []models.Issue
+	insert  bool // The invoice is new and prepared, waiting to be inserted
+}
+
+// insertQueued inserts the new invoices of the queued rows together under a savepoint. If that fails,
+// as when another import took one of the numbers meanwhile, each is saved on its own instead, so the
+// error is reported on its row.
+func insertQueued(tx *sql.Tx, queue []*queuedRow, onConflict string) {
+	var rows []*queuedRow
+	var invoices []*models.Invoice
+	for _, row := range queue {
+		if row.insert {
+			rows = append(rows, row)
+			invoices = append(invoices, &row.invoice)
+		}
+	}
+	if len(invoices) == 0 {
+		return
+	}
+	err := repository.WithSavepoint(tx, "import_batch", func() error {
+		return repository.InsertInvoices(tx, invoices)
+	})
+	for _, row := range rows {
+		if err == nil {
+			row.action = models.ImportActionCreate
+			continue
+		}
+		var rowErr error
+		if row.action, row.issues, rowErr = saveInvoice(tx, &row.invoice, onConflict); rowErr != nil {
+			locateError(rowErr, row.readers[0], row.readers[1:])
 		}
 	}
+}
 
-	return errors, nil
+// importResult builds the result of an import. The rows, errors and warnings past the limit are
+// counted but not listed.
+type importResult struct {
+	*models.ImportResult
+	limit        int
+	rejectedRows rowRanges
 }
 
-// validateAndInsertInvoice validates and inserts invoice data into the database
-func validateAndInsertInvoice(row []string, products []models.Product, is *ImportService) error {
-	if len(row) < 6 || row[0] == "" || row[1] == "" || row[2] == "" || row[3] == "" || row[4] == "" || row[5] == "" {
-		return fmt.Errorf("missing required invoice fields")
+// listRow counts an invoice row as processed, and lists its outcome if the limit allows.
+func (r *importResult) listRow(record models.ImportRow) {
+	r.Processed++
+	if len(r.Rows) < r.limit {
+		r.Rows = append(r.Rows, record)
+	} else {
+		r.Truncated = true
 	}
+}
 
-	parsedDate, err := time.Parse("02-01-06", row[1]) // Adjust the layout based on your date format
-	if err != nil {
-		return fmt.Errorf("invalid date format: %w", err)
+// finish lists the outcome of a queued row: rejected with the errors its readers hold, or saved.
+func (r *importResult) finish(row *queuedRow, dryRun bool) {
+	record := row.record
+	if errs := collectErrors(row.readers); len(errs) > 0 {
+		record.Action = models.ImportActionReject
+		record.Errors = errs
+		r.reject(record)
+		return
 	}
 
-	exists, err := repository.CheckInvoiceExists(is.DB, row[0])
-	if err != nil {
-		return fmt.Errorf("error checking invoice duplication: %w", err)
+	record.InvoiceNo = row.invoice.InvoiceNo
+	record.Action = row.action
+	record.Warnings = row.issues
+	switch row.action {
+	case models.ImportActionSkip:
+		r.Skipped++ // The invoice already exists and is left as it is
+		r.listRow(record)
+		return
+	case models.ImportActionUpdate:
+		r.Updated++
+	default:
+		r.Created++
+	}
+	if dryRun && len(r.Rows) < r.limit {
+		// Preview the invoice a real import would save; a real import reports only its number
+		response := models.NewInvoiceResponse(row.invoice)
+		record.Invoice = &response
+	}
+	for _, issue := range row.issues {
+		r.listWarning(map[string]string{
+			"row":        strconv.Itoa(record.Row),
+			"invoice_id": row.invoice.InvoiceNo,
+			"field":      issue.Field,
+			"rule":       issue.Rule,
+			"warning":    issue.Message,
+		})
+	}
+	r.listRow(record)
+}
+
+// reject counts an invoice row as rejected and lists it with its errors.
+func (r *importResult) reject(record models.ImportRow) {
+	r.Rejected++
+	r.rejectedRows.add(record.Row)
+	r.listErrors(record.Errors)
+	r.listRow(record)
+}
+
+// listErrors lists the errors of a row, as many as the limit allows.
+func (r *importResult) listErrors(errs []models.ImportError) {
+	room := max(r.limit-len(r.Errors), 0)
+	if len(errs) > room {
+		errs, r.Truncated = errs[:room], true
+	}
+	r.Errors = append(r.Errors, errs...)
+}
+
+// listWarning lists a warning if the limit allows.
+func (r *importResult) listWarning(warning map[string]string) {
+	if len(r.Warnings) < r.limit {
+		r.Warnings = append(r.Warnings, warning)
+	} else {
+		r.Truncated = true
+	}
+}
+
+// rowRanges holds a set of rows as ranges of consecutive rows, added in increasing order.
+type rowRanges [][2]int
+
+// add adds a row after the rows already added.
+func (rr *rowRanges) add(row int) {
+	if n := len(*rr); n > 0 && (*rr)[n-1][1] >= row-1 {
+		(*rr)[n-1][1] = max((*rr)[n-1][1], row)
+		return
+	}
+	*rr = append(*rr, [2]int{row, row})
+}
+
+// String writes the ranges as in 3,7-9.
+func (rr rowRanges) String() string {
+	parts := make([]string, len(rr))
+	for i, r := range rr {
+		parts[i] = strconv.Itoa(r[0])
+		if r[1] > r[0] {
+			parts[i] += "-" + strconv.Itoa(r[1])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// contains reports whether a row is in one of the ranges.
+func (rr rowRanges) contains(row int) bool {
+	i := sort.Search(len(rr), func(i int) bool { return rr[i][1] >= row })
+	return i < len(rr) && rr[i][0] <= row
+}
+
+// parseRowRanges reads ranges written by rowRanges.String, skipping any part that isn't a row or a range.
+func parseRowRanges(s string) rowRanges {
+	var rr rowRanges
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				continue
+			}
+		}
+		rr = append(rr, [2]int{first, last})
 	}
-	if exists {
-		return fmt.Errorf("duplicate invoice ID found")
+	return rr
+}
+
+// saveInvoice saves the invoice inside tx under a savepoint, so a rejected row is undone without
+// losing the rows before it and the rows after it are still checked. It returns the action taken.
+func saveInvoice(tx *sql.Tx, invoice *models.Invoice, onConflict string) (string, []models.Issue, error) {
+	var action string
+	var issues []models.Issue
+	err := repository.WithSavepoint(tx, "import_row", func() error {
+		var err error
+		action, issues, err = repository.SaveInvoice(tx, invoice, onConflict)
+		return err
+	})
+	return action, issues, err
+}
+
+// onConflict returns how an import handles invoice numbers already taken, rejecting them by default.
+func onConflict(options models.ImportRequest) string {
+	if options.OnConflict == "" {
+		return models.ImportConflictError
+	}
+	return options.OnConflict
+}
+
+// parseInvoiceRow builds the invoice of a row of the invoice sheet with its products
+func parseInvoiceRow(r *rowReader, products []models.Product) models.Invoice {
+	// Missing or invalid fields are reported by the invoice rules in CreateInvoice, as for the API.
+	// Only the invoice number, which links the sheets, and the cells that have to be parsed are checked here.
+	if r.invoiceNo == "" {
+		r.errorAt("invoice_no", models.ImportErrorRequired, "invoice no is required to link the invoice to its products")
 	}
 
 	//attach invoice
-	invoice := models.Invoice{
-		InvoiceNo:       row[0],
-		Date:            parsedDate,
-		CustomerName:    row[2],
-		SalespersonName: row[3],
-		PaymentType:     row[4],
-		Notes:           row[5],
+	return models.Invoice{
+		InvoiceNo:       r.invoiceNo,
+		Date:            r.date("date"),
+		CustomerName:    r.text("customer_name"),
+		SalespersonName: r.text("salesperson_name"),
+		PaymentType:     r.text("payment_type"),
+		Notes:           r.text("notes"),
+		TaxMode:         strings.ToLower(r.text("tax_mode")),
+		Currency:        strings.ToUpper(r.text("currency")),
+		DueDate:         r.optionalDate("due_date"), // credit invoices without one get the default payment terms
+		Status:          models.InvoiceStatusIssued, // imported invoices were already sent to customers
 		Products:        products,
 	}
+}
 
-	return repository.CreateInvoice(is.DB, invoice)
+// parseProductRow builds the product of a row of the product sheet.
+// Either the totals or the unit values may be filled in.
+func parseProductRow(r *rowReader) models.Product {
+	return models.Product{
+		InvoiceNo:       r.invoiceNo,
+		ItemName:        r.text("item_name"),
+		Quantity:        r.integer("quantity"),
+		UnitCost:        r.amount("unit_cost"),
+		UnitPrice:       r.amount("unit_price"),
+		DiscountAmount:  r.amount("discount_amount"),
+		DiscountPercent: r.percent("discount_percent"),
+		TotalCost:       r.amount("total_cost"),
+		TotalPrice:      r.amount("total_price"),
+		TaxCode:         r.text("tax_code"),
+	}
+}
+
+// locateError records why an invoice couldn't be created against the cells involved:
+// each broken rule against the column of its field, in the invoice row or the product row it concerns.
+func locateError(err error, invoiceRow *rowReader, productRows []*rowReader) {
+	var validationErr *repository.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		for _, issue := range validationErr.Issues {
+			if issue.Severity != models.SeverityError {
+				continue
+			}
+			reader, field := invoiceRow, issue.Field
+			if n, productField, ok := productField(issue.Field); ok && n < len(productRows) {
+				reader, field = productRows[n], productField
+			}
+			reader.errorAt(field, issue.Rule, issue.Message)
+		}
+	case errors.Is(err, repository.ErrInvoiceExists):
+		invoiceRow.errorAt("invoice_no", models.ImportErrorDuplicate, err.Error())
+	case errors.Is(err, repository.ErrInvoiceNotEditable):
+		invoiceRow.errorAt("invoice_no", models.ImportErrorNotEditable, err.Error())
+	default:
+		invoiceRow.errorAt("", models.ImportErrorFailed, err.Error())
+	}
+}
+
+// productField splits a product issue field such as products[2].quantity into the product index and its field.
+func productField(field string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(field, "products[")
+	if !ok {
+		return 0, "", false
+	}
+	index, name, ok := strings.Cut(rest, "].")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, "", false
+	}
+	return n, name, true
+}
+
+// collectErrors returns the errors recorded by the readers of an invoice row and its product rows.
+func collectErrors(readers []*rowReader) []models.ImportError {
+	var errs []models.ImportError
+	for _, reader := range readers {
+		errs = append(errs, reader.errors...)
+	}
+	return errs
+}
+
+// isEmptyRow reports whether every cell of the row is blank.
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cellValue returns the trimmed cell at index i, or "" when the row is shorter.
+// excelize drops trailing empty cells, so optional columns may be missing entirely.
+func cellValue(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// parseAmount parses an optional money cell, treating an empty cell as zero.
+func parseAmount(value string) (models.Money, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return models.ParseMoney(value)
+}
+
+// parsePercent parses an optional percentage cell, treating an empty cell as zero.
+func parsePercent(value string) (models.Percent, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return models.ParsePercent(value)
 }
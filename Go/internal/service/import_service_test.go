@@ -0,0 +1,267 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+	"widatech-technical-challenge/database"
+	"widatech-technical-challenge/internal/models"
+
+	_ "github.com/lib/pq"
+	"github.com/xuri/excelize/v2"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL and migrates it,
+// skipping the test when it isn't set.
+func testDB(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DBMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// benchmarkWorkbook writes an import workbook of invoices numbered from prefix with two product rows
+// each, streamed so building it doesn't weigh on the benchmark.
+func benchmarkWorkbook(tb testing.TB, prefix string, invoices int) []byte {
+	tb.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for _, sheet := range []importSheet{invoiceSheet, productSheet} {
+		if _, err := f.NewSheet(sheet.Name); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	f.DeleteSheet("Sheet1")
+
+	write := func(sheet string, header []any, rows func(sw *excelize.StreamWriter) error) {
+		sw, err := f.NewStreamWriter(sheet)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := sw.SetRow("A1", header); err != nil {
+			tb.Fatal(err)
+		}
+		if err := rows(sw); err != nil {
+			tb.Fatal(err)
+		}
+		if err := sw.Flush(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	write(invoiceSheet.Name, []any{"invoice no", "date", "customer", "salesperson", "payment type", "notes"}, func(sw *excelize.StreamWriter) error {
+		for i := 0; i < invoices; i++ {
+			cell, _ := excelize.CoordinatesToCellName(1, i+2)
+			row := []any{fmt.Sprintf("%s%d", prefix, i), "2021-01-01", "John", "Doe", "CASH", "Lorem ipsum"}
+			if err := sw.SetRow(cell, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	write(productSheet.Name, []any{"invoice no", "item", "quantity", "total cogs", "total price"}, func(sw *excelize.StreamWriter) error {
+		for i := 0; i < invoices*2; i++ {
+			cell, _ := excelize.CoordinatesToCellName(1, i+2)
+			row := []any{fmt.Sprintf("%s%d", prefix, i/2), fmt.Sprintf("Product %d", i%2), 2, 10000, 15000}
+			if err := sw.SetRow(cell, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkProcessXLSXFile imports a workbook of 100,000 invoices with 200,000 product rows, reporting
+// the allocations of the import and the peak of the heap in use above what it started with.
+func BenchmarkProcessXLSXFile(b *testing.B) {
+	db := testDB(b)
+	prefix := fmt.Sprintf("BENCH-%d-", time.Now().UnixNano())
+	deleteInvoices := func() {
+		if _, err := db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Cleanup(deleteInvoices)
+	workbook := benchmarkWorkbook(b, prefix, 100000)
+	is := NewImportService(db)
+
+	var peak uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result *models.ImportResult
+		var err error
+		peak = max(peak, peakHeapGrowth(func() {
+			result, err = is.ProcessXLSXFile(context.Background(), bytes.NewReader(workbook), models.ImportRequest{})
+		}))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.Created != 100000 || result.Rejected != 0 {
+			b.Fatalf("created %d invoices and rejected %d rows, want 100000 and 0: %v", result.Created, result.Rejected, result.Errors)
+		}
+		b.StopTimer()
+		deleteInvoices()
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+}
+
+// importHeapLimit is how far the heap in use may grow above what it started with while a large workbook
+// is imported. The rows are staged in the database and read back a page at a time, so this doesn't
+// depend on the number of rows.
+const importHeapLimit = 64 << 20
+
+// TestProcessXLSXFileBoundedMemory imports a workbook of 100,000 invoices with 200,000 product rows
+// and checks that the heap in use stays within importHeapLimit of what it started with.
+func TestProcessXLSXFileBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("imports 100,000 invoices")
+	}
+	db := testDB(t)
+	prefix := fmt.Sprintf("MEMORY-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+	workbook := benchmarkWorkbook(t, prefix, 100000)
+	is := NewImportService(db)
+
+	var result *models.ImportResult
+	var err error
+	peak := peakHeapGrowth(func() {
+		result, err = is.ProcessXLSXFile(context.Background(), bytes.NewReader(workbook), models.ImportRequest{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 100000 || result.Rejected != 0 {
+		t.Fatalf("created %d invoices and rejected %d rows, want 100000 and 0: %v", result.Created, result.Rejected, result.Errors)
+	}
+	if peak > importHeapLimit {
+		t.Errorf("the heap in use grew by %d MiB during the import, want at most %d MiB", peak>>20, importHeapLimit>>20)
+	}
+}
+
+// peakHeapGrowth runs fn and returns how far the heap in use peaked above what it was before,
+// sampled every 10ms while fn runs.
+func peakHeapGrowth(fn func()) uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base, peak := stats.HeapInuse, stats.HeapInuse
+
+	done, sampled := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			peak = max(peak, stats.HeapInuse)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-sampled
+	return peak - base
+}
+
+// TestProcessXLSXFileReimports imports a workbook, then imports it again with update and with replace,
+// which must revise the issued invoices the first import created rather than reject them.
+func TestProcessXLSXFileReimports(t *testing.T) {
+	db := testDB(t)
+	prefix := fmt.Sprintf("REIMPORT-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM invoices WHERE invoice_no LIKE $1`, prefix+"%")
+	})
+	workbook := benchmarkWorkbook(t, prefix, 3)
+	is := NewImportService(db)
+
+	result, err := is.ProcessXLSXFile(context.Background(), bytes.NewReader(workbook), models.ImportRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 3 {
+		t.Fatalf("first import created %d invoices, want 3: %v", result.Created, result.Errors)
+	}
+	for _, onConflict := range []string{models.ImportConflictUpdate, models.ImportConflictReplace} {
+		result, err := is.ProcessXLSXFile(context.Background(), bytes.NewReader(workbook), models.ImportRequest{OnConflict: onConflict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Updated != 3 || result.Rejected != 0 {
+			t.Errorf("importing again with %s updated %d and rejected %d, want 3 and 0: %v", onConflict, result.Updated, result.Rejected, result.Errors)
+		}
+	}
+}
+
+func TestRowRanges(t *testing.T) {
+	var rr rowRanges
+	for _, row := range []int{3, 7, 8, 9, 12, 13} {
+		rr.add(row)
+	}
+	if got, want := rr.String(), "3,7-9,12-13"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	parsed := parseRowRanges(rr.String())
+	for row := 1; row <= 14; row++ {
+		want := row == 3 || (row >= 7 && row <= 9) || row == 12 || row == 13
+		if got := parsed.contains(row); got != want {
+			t.Errorf("contains(%d) = %v, want %v", row, got, want)
+		}
+	}
+	if got := parseRowRanges(""); len(got) != 0 {
+		t.Errorf("parseRowRanges(\"\") = %v, want no ranges", got)
+	}
+}
+
+func TestProcessXLSXFileRejectsOtherArchives(t *testing.T) {
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	if f, err := w.Create("word/document.xml"); err != nil {
+		t.Fatal(err)
+	} else {
+		f.Write([]byte("<document/>"))
+	}
+	w.Close()
+
+	is := NewImportService(nil)
+	for name, file := range map[string][]byte{"a zip archive without a workbook": archive.Bytes(), "a truncated zip archive": archive.Bytes()[:40]} {
+		_, err := is.ProcessXLSXFile(context.Background(), bytes.NewReader(file), models.ImportRequest{})
+		var fileErr *ImportFileError
+		if !errors.As(err, &fileErr) || fileErr.Errors[0].Code != models.ImportErrorInvalidXLSX {
+			t.Errorf("%s: err = %v, want an invalid_xlsx file error", name, err)
+		}
+	}
+}
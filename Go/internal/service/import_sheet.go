@@ -0,0 +1,418 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// importColumn is a column of an import sheet: the invoice or product field it fills and the headers it is found by.
+type importColumn struct {
+	Field    string
+	Header   string   // Header of the column in the template
+	Synonyms []string // Other headers accepted for the column
+	Required bool     // The sheet is rejected without this column
+	Date     bool     // The column holds dates, formatted as such in the template
+	Help     string   // What to fill in, for the instructions of the template
+}
+
+// importSheet describes a sheet of the import workbook and its columns.
+type importSheet struct {
+	Name     string   // Name of the sheet in the template
+	Synonyms []string // Other names accepted for the sheet
+	Columns  []importColumn
+}
+
+var invoiceSheet = importSheet{
+	Name:     "invoice",
+	Synonyms: []string{"invoices"},
+	Columns: []importColumn{
+		{Field: "invoice_no", Header: "invoice no", Synonyms: []string{"invoice number", "invoice id", "invoice"}, Required: true,
+			Help: "Number of the invoice, unique in the sheet. Its product rows have the same number."},
+		{Field: "date", Header: "date", Synonyms: []string{"invoice date"}, Required: true, Date: true,
+			Help: "Date of the invoice."},
+		{Field: "customer_name", Header: "customer", Synonyms: []string{"customer name", "client"}, Required: true,
+			Help: "Name of the customer."},
+		{Field: "salesperson_name", Header: "salesperson", Synonyms: []string{"salesperson name", "sales person", "sales"}, Required: true,
+			Help: "Name of the salesperson."},
+		{Field: "payment_type", Header: "payment type", Synonyms: []string{"payment method", "payment"}, Required: true,
+			Help: "Code of an active payment method, picked from the list."},
+		{Field: "notes", Header: "notes", Synonyms: []string{"note", "remarks"},
+			Help: "Optional notes."},
+		{Field: "tax_mode", Header: "tax mode",
+			Help: "Whether the product prices exclude or include tax: exclusive (default) or inclusive."},
+		{Field: "currency", Header: "currency",
+			Help: "ISO 4217 code of the currency of the amounts, e.g. USD. Defaults to the base currency."},
+		{Field: "due_date", Header: "due date", Date: true,
+			Help: "Date a credit invoice must be paid by. Defaults to the payment terms."},
+	},
+}
+
+var productSheet = importSheet{
+	Name:     "product sold",
+	Synonyms: []string{"products sold", "products", "product"},
+	Columns: []importColumn{
+		{Field: "invoice_no", Header: "invoice no", Synonyms: []string{"invoice number", "invoice id", "invoice"}, Required: true,
+			Help: "Number of the invoice the product was sold on, as in the invoice sheet."},
+		{Field: "item_name", Header: "item", Synonyms: []string{"item name", "product", "product name"}, Required: true,
+			Help: "Name of the product."},
+		{Field: "quantity", Header: "quantity", Synonyms: []string{"qty"}, Required: true,
+			Help: "Quantity sold, a whole number."},
+		{Field: "total_cost", Header: "total cogs", Synonyms: []string{"total cost", "cogs"},
+			Help: "Cost of the goods sold on the line. Fill in either the totals or the unit values."},
+		{Field: "total_price", Header: "total price",
+			Help: "Price of the line after discount."},
+		{Field: "unit_cost", Header: "unit cogs", Synonyms: []string{"unit cost"},
+			Help: "Cost of one unit, instead of the total cost."},
+		{Field: "unit_price", Header: "unit price",
+			Help: "Price of one unit, instead of the total price."},
+		{Field: "discount_amount", Header: "discount", Synonyms: []string{"discount amount"},
+			Help: "Discount on the line as an amount."},
+		{Field: "discount_percent", Header: "discount %", Synonyms: []string{"discount percent", "discount percentage"},
+			Help: "Discount on the line in percent, e.g. 10 for 10%, instead of the discount amount."},
+		{Field: "tax_code", Header: "tax code",
+			Help: "Code of the tax rate of the product. Leave empty for no tax."},
+	},
+}
+
+// ImportFileError rejects a file whose layout doesn't match the import: a sheet or a required column
+// is missing, or a column appears twice. It is reported before any row is processed.
+type ImportFileError struct {
+	Errors []models.ImportError
+}
+
+func (e *ImportFileError) Error() string {
+	return fmt.Sprintf("the file doesn't match the import layout: %s", e.Errors[0].Message)
+}
+
+// ImportFields returns the fields the import reads, which import profiles may map and default.
+func ImportFields() []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, sheet := range []importSheet{invoiceSheet, productSheet} {
+		for _, column := range sheet.Columns {
+			if !seen[column.Field] {
+				fields = append(fields, column.Field)
+				seen[column.Field] = true
+			}
+		}
+	}
+	return fields
+}
+
+// importFormat is how the values of a file are written, from its import profile.
+type importFormat struct {
+	profile          models.ImportProfile
+	dateFormats      []utils.DateFormat
+	date1904         bool // The workbook counts date serials from 1904
+	decimalSeparator string
+}
+
+// newImportFormat reads the format of a file from its import profile, or the defaults without one.
+func newImportFormat(profile models.ImportProfile) (importFormat, error) {
+	format := importFormat{profile: profile, dateFormats: utils.ImportDateFormats(), decimalSeparator: "."}
+	if profile.DateFormat != "" {
+		dateFormats, err := utils.ParseDateFormats(profile.DateFormat)
+		if err != nil {
+			return format, err
+		}
+		format.dateFormats = dateFormats
+	}
+	if profile.DecimalSeparator != "" {
+		format.decimalSeparator = profile.DecimalSeparator
+	}
+	return format, nil
+}
+
+// dateFormatList returns the date formats of the file as its user writes them.
+func (f importFormat) dateFormatList() string {
+	formats := make([]string, len(f.dateFormats))
+	for i, format := range f.dateFormats {
+		formats[i] = format.Format
+	}
+	return strings.Join(formats, ", ")
+}
+
+// sheetLayout is an import sheet as found in a file: its actual name, its header row and the column of each field.
+type sheetLayout struct {
+	importSheet
+	format  importFormat
+	name    string
+	header  []string
+	columns map[string]int
+}
+
+// findSheet returns the name of the sheet of the workbook matching the import sheet, ignoring case,
+// spacing and underscores, so "product_sold" is found as "product sold". A profile's sheet name
+// replaces the default names.
+func findSheet(f *excelize.File, sheet importSheet, profileName string) (string, bool) {
+	names := map[string]bool{utils.NormalizeHeader(sheet.Name): true}
+	for _, name := range sheet.Synonyms {
+		names[utils.NormalizeHeader(name)] = true
+	}
+	if profileName != "" {
+		names = map[string]bool{utils.NormalizeHeader(profileName): true}
+	}
+	for _, name := range f.GetSheetList() {
+		if names[utils.NormalizeHeader(name)] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// newSheetLayout maps the header row of a sheet onto its columns by header or synonym, and by the
+// headers of the import profile, which take precedence. Headers that match no column are ignored.
+// It returns an error for each required column that is missing and has no default, and each column found twice.
+func newSheetLayout(sheet importSheet, format importFormat, name string, header []string) (*sheetLayout, []models.ImportError) {
+	layout := &sheetLayout{importSheet: sheet, format: format, name: name, header: header, columns: map[string]int{}}
+	extra := utils.ImportHeaderSynonyms()
+
+	fields := map[string]string{}
+	for _, column := range sheet.Columns {
+		headers := append([]string{column.Field, column.Header}, column.Synonyms...)
+		for _, h := range append(headers, extra[column.Field]...) {
+			fields[utils.NormalizeHeader(h)] = column.Field
+		}
+	}
+	for _, column := range sheet.Columns {
+		if h, ok := format.profile.Columns[column.Field]; ok {
+			fields[utils.NormalizeHeader(h)] = column.Field
+		}
+	}
+
+	var errs []models.ImportError
+	for i, h := range header {
+		field, ok := fields[utils.NormalizeHeader(h)]
+		if !ok {
+			continue
+		}
+		if first, seen := layout.columns[field]; seen {
+			errs = append(errs, layout.headerError(i, models.ImportErrorDuplicateColumn,
+				fmt.Sprintf("%s is already read from column %s", field, columnName(first))))
+			continue
+		}
+		layout.columns[field] = i
+	}
+	for _, column := range sheet.Columns {
+		_, found := layout.columns[column.Field]
+		_, defaulted := format.profile.Defaults[column.Field]
+		if column.Required && !found && !defaulted {
+			expected := column.Header
+			if h, ok := format.profile.Columns[column.Field]; ok {
+				expected = h
+			}
+			errs = append(errs, models.ImportError{
+				Sheet:   name,
+				Row:     1,
+				Header:  expected,
+				Code:    models.ImportErrorMissingColumn,
+				Message: fmt.Sprintf("required column %q is missing", expected),
+			})
+		}
+	}
+	return layout, errs
+}
+
+// headerError returns an error on the header cell of column i.
+func (l *sheetLayout) headerError(i int, code, message string) models.ImportError {
+	return models.ImportError{
+		Sheet:   l.name,
+		Row:     1,
+		Column:  columnName(i),
+		Header:  cellValue(l.header, i),
+		Code:    code,
+		Message: message,
+	}
+}
+
+// column returns the index of the column holding field, or -1 if the sheet has none.
+func (l *sheetLayout) column(field string) int {
+	if i, ok := l.columns[field]; ok {
+		return i
+	}
+	return -1
+}
+
+// columnName returns the letter of the column at index i, e.g. C for 2.
+func columnName(i int) string {
+	name, _ := excelize.ColumnNumberToName(i + 1)
+	return name
+}
+
+// rowReader reads the cells of one row of a sheet. Cells that can't be parsed read as zero values
+// and are recorded as errors located by sheet, row and column.
+type rowReader struct {
+	layout    *sheetLayout
+	cells     []string // Values as displayed
+	raw       []string // Values as stored, without number formats; date cells hold their serial
+	row       int      // 1-based row number
+	invoiceNo string
+	errors    []models.ImportError
+}
+
+// newRowReader returns a reader of a row of a sheet with the given layout.
+func newRowReader(layout *sheetLayout, cells, raw []string, row int) *rowReader {
+	r := &rowReader{layout: layout, cells: cells, raw: raw, row: row}
+	r.invoiceNo = r.text("invoice_no")
+	return r
+}
+
+// importTable is an import sheet or CSV file being read: its layout, from the header, and the source
+// of the rows after it.
+type importTable struct {
+	layout *sheetLayout
+	source rowSource
+	row    int          // 1-based number of the last row read
+	queue  []*rowReader // Rows read ahead: empty rows wait for a row with values after them
+}
+
+// openTable reads the header of a table from the first row of its source and maps its columns.
+func openTable(sheet importSheet, format importFormat, name string, source rowSource) (*importTable, []models.ImportError) {
+	var header []string
+	if source.Next() {
+		header, _ = source.Row()
+	}
+	if err := source.Err(); err != nil {
+		return nil, []models.ImportError{readError(name, 1, err)}
+	}
+	layout, errs := newSheetLayout(sheet, format, name, header)
+	return &importTable{layout: layout, source: source, row: 1}, errs
+}
+
+// next returns a reader of the next row of the table. Empty rows at the end of the table are dropped,
+// as they are outside the range a spreadsheet considers used.
+func (t *importTable) next() (*rowReader, bool) {
+	for len(t.queue) == 0 || isEmptyRow(t.queue[len(t.queue)-1].cells) {
+		if !t.source.Next() {
+			return nil, false
+		}
+		t.row++
+		cells, raw := t.source.Row()
+		t.queue = append(t.queue, newRowReader(t.layout, cells, raw, t.row))
+	}
+	r := t.queue[0]
+	t.queue = t.queue[1:]
+	return r, true
+}
+
+// readError returns the error that stopped the rows of the table, if any.
+func (t *importTable) readError() *models.ImportError {
+	if err := t.source.Err(); err != nil {
+		importErr := readError(t.layout.name, t.row+1, err)
+		return &importErr
+	}
+	return nil
+}
+
+// text returns the trimmed cell of field, or the profile's default when it is empty.
+func (r *rowReader) text(field string) string {
+	if value := cellValue(r.cells, r.layout.column(field)); value != "" {
+		return value
+	}
+	return r.layout.format.profile.Defaults[field]
+}
+
+// errorAt records an error on the cell of field, or on the whole row if field has no column.
+func (r *rowReader) errorAt(field, code, message string) {
+	importErr := models.ImportError{
+		Sheet:     r.layout.name,
+		Row:       r.row,
+		Code:      code,
+		InvoiceNo: r.invoiceNo,
+		Message:   message,
+	}
+	if i := r.layout.column(field); i >= 0 {
+		importErr.Column = columnName(i)
+		importErr.Header = cellValue(r.layout.header, i)
+		importErr.Value = cellValue(r.cells, i)
+	}
+	r.errors = append(r.errors, importErr)
+}
+
+// integer parses an optional whole-number cell, treating an empty cell as zero.
+func (r *rowReader) integer(field string) int {
+	value := r.text(field)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.errorAt(field, models.ImportErrorInvalidNumber, fmt.Sprintf("%s must be a whole number", field))
+	}
+	return n
+}
+
+// decimal returns an optional decimal cell in the form the money parser reads, or "" when it is empty.
+func (r *rowReader) decimal(field string) (string, error) {
+	value := r.text(field)
+	if value == "" {
+		return "", nil
+	}
+	return utils.NormalizeDecimal(value, r.layout.format.decimalSeparator)
+}
+
+// amount parses an optional money cell, treating an empty cell as zero.
+func (r *rowReader) amount(field string) models.Money {
+	value, err := r.decimal(field)
+	var amount models.Money
+	if err == nil {
+		amount, err = parseAmount(value)
+	}
+	if err != nil {
+		r.errorAt(field, models.ImportErrorInvalidNumber, fmt.Sprintf("%s must be an amount", field))
+	}
+	return amount
+}
+
+// percent parses an optional percentage cell, treating an empty cell as zero.
+func (r *rowReader) percent(field string) models.Percent {
+	value, err := r.decimal(field)
+	var percent models.Percent
+	if err == nil {
+		percent, err = parsePercent(value)
+	}
+	if err != nil {
+		r.errorAt(field, models.ImportErrorInvalidNumber, fmt.Sprintf("%s must be a percentage", field))
+	}
+	return percent
+}
+
+// date parses an optional date cell, leaving an empty cell to the required-field rules. Native date
+// cells and numbers are read as Excel serials in the date system of the workbook, text in the date formats.
+func (r *rowReader) date(field string) time.Time {
+	value := r.text(field)
+	if value == "" {
+		return time.Time{}
+	}
+	if serial, ok := utils.ParseExcelSerial(cellValue(r.raw, r.layout.column(field))); ok {
+		date, err := utils.ExcelSerialDate(serial, r.layout.format.date1904)
+		if err != nil {
+			r.errorAt(field, models.ImportErrorInvalidDate, fmt.Sprintf("%s: %v", field, err))
+		}
+		return date
+	}
+	date, err := utils.ParseImportDate(value, r.layout.format.dateFormats)
+	if errors.Is(err, utils.ErrAmbiguousDate) {
+		r.errorAt(field, models.ImportErrorAmbiguousDate, fmt.Sprintf("%s: %v", field, err))
+	} else if err != nil {
+		r.errorAt(field, models.ImportErrorInvalidDate,
+			fmt.Sprintf("%s must be a date cell or a date in one of the formats %s", field, r.layout.format.dateFormatList()))
+	}
+	return date
+}
+
+// optionalDate parses an optional date cell, returning nil when it is empty.
+func (r *rowReader) optionalDate(field string) *time.Time {
+	if r.text(field) == "" {
+		return nil
+	}
+	date := r.date(field)
+	return &date
+}
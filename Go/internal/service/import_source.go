@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// rowSource reads the rows of a sheet or CSV file one at a time, so a file is never held in memory whole.
+type rowSource interface {
+	// Next moves to the next row, returning false at the end of the rows or when reading fails.
+	Next() bool
+	// Row returns the cells of the current row as displayed, and as stored (nil when the source has no stored values).
+	Row() (cells, raw []string)
+	// Err returns the error that stopped Next, if any.
+	Err() error
+	Close() error
+}
+
+// sheetRows streams the rows of a worksheet with two iterators in lockstep, one reading the values
+// as displayed and one as stored, which holds the serial of date cells.
+type sheetRows struct {
+	display, stored *excelize.Rows
+	cells, raw      []string
+	err             error
+}
+
+// openSheetRows starts streaming the rows of a worksheet.
+func openSheetRows(f *excelize.File, name string) (*sheetRows, error) {
+	display, err := f.Rows(name)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := f.Rows(name)
+	if err != nil {
+		display.Close()
+		return nil, err
+	}
+	return &sheetRows{display: display, stored: stored}, nil
+}
+
+func (s *sheetRows) Next() bool {
+	if s.err != nil || !s.display.Next() || !s.stored.Next() {
+		return false
+	}
+	if s.cells, s.err = s.display.Columns(); s.err != nil {
+		return false
+	}
+	s.raw, s.err = s.stored.Columns(excelize.Options{RawCellValue: true})
+	return s.err == nil
+}
+
+func (s *sheetRows) Row() ([]string, []string) { return s.cells, s.raw }
+
+func (s *sheetRows) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.display.Error(); err != nil {
+		return err
+	}
+	return s.stored.Error()
+}
+
+func (s *sheetRows) Close() error {
+	return errors.Join(s.display.Close(), s.stored.Close())
+}
+
+// csvRows streams the records of a CSV file.
+type csvRows struct {
+	reader *csv.Reader
+	cells  []string
+	err    error
+}
+
+// newCSVRows starts reading a CSV file, decoded to UTF-8 by utils.DecodeCSV. The delimiter is
+// detected from the header line.
+func newCSVRows(file io.Reader) *csvRows {
+	buffered := bufio.NewReader(utils.DecodeCSV(file))
+	// Look ahead at the header line without consuming it; a longer line is sniffed from its start
+	head, _ := buffered.Peek(buffered.Size())
+	firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = utils.CSVDelimiter(string(firstLine))
+	reader.FieldsPerRecord = -1 // Rows may leave out trailing empty cells, like in a workbook
+	return &csvRows{reader: reader}
+}
+
+func (c *csvRows) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	c.cells, c.err = c.reader.Read()
+	if c.err == io.EOF {
+		c.err = nil
+		c.cells = nil
+		return false
+	}
+	return c.err == nil
+}
+
+func (c *csvRows) Row() ([]string, []string) { return c.cells, nil }
+
+func (c *csvRows) Err() error { return c.err }
+
+func (c *csvRows) Close() error { return nil }
+
+// readError locates an error reading the rows of a sheet or file, at row when the reader can't tell.
+func readError(name string, row int, err error) models.ImportError {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return models.ImportError{Sheet: name, Row: parseErr.StartLine, Code: models.ImportErrorInvalidCSV, Message: err.Error()}
+	}
+	return models.ImportError{Sheet: name, Row: row, Code: models.ImportErrorFailed, Message: err.Error()}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// templateInstructions is the name of the sheet of the template that explains how to fill it in.
+const templateInstructions = "instructions"
+
+// templateHeaderFill marks the header rows of the template.
+var templateHeaderFill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}}
+
+// templateInvoices and templateProducts are the example rows of the template, by field.
+// The payment types are filled in from the payment methods.
+var (
+	templateInvoices = []map[string]any{
+		{"invoice_no": "INV-001", "date": time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "customer_name": "John",
+			"salesperson_name": "Doe", "notes": "Lorem ipsum"},
+		{"invoice_no": "INV-002", "date": time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), "customer_name": "Jane",
+			"salesperson_name": "Smith", "due_date": time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	templateProducts = []map[string]any{
+		{"invoice_no": "INV-001", "item_name": "Bluetooth speaker", "quantity": 3, "total_cost": 630000, "total_price": 756000},
+		{"invoice_no": "INV-001", "item_name": "Headphone", "quantity": 8, "total_cost": 400000, "total_price": 480000},
+		{"invoice_no": "INV-002", "item_name": "Laptop charger", "quantity": 4, "unit_cost": 125000, "unit_price": 150000,
+			"discount_percent": 10},
+	}
+)
+
+// ImportTemplate returns an import workbook to fill in, laid out with the import profile named in the
+// options, if any. It is built from the column definitions the import reads: a sheet per import sheet
+// with the header of each column, date formats on the date columns and a dropdown of the active payment
+// methods, followed by example rows, and an instructions sheet describing each column.
+func (is *ImportService) ImportTemplate(options models.ImportRequest) ([]byte, error) {
+	format, err := is.loadFormat(options)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethods, err := repository.GetPaymentMethods(is.DB)
+	if err != nil {
+		return nil, err
+	}
+	var active []models.PaymentMethod
+	for _, paymentMethod := range paymentMethods {
+		if paymentMethod.Active {
+			active = append(active, paymentMethod)
+		}
+	}
+	return buildTemplate(format, active)
+}
+
+// buildTemplate writes the import template of a format, with a dropdown of the given payment methods.
+func buildTemplate(format importFormat, active []models.PaymentMethod) ([]byte, error) {
+	var err error
+	f := excelize.NewFile()
+	defer f.Close()
+	w := &templateWriter{f: f, format: format}
+	if w.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, Fill: templateHeaderFill}); err != nil {
+		return nil, err
+	}
+	dateFormat := "yyyy-mm-dd"
+	if format.profile.DateFormat != "" {
+		dateFormat = strings.ToLower(format.dateFormats[0].Format)
+	}
+	if w.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return nil, err
+	}
+
+	invoiceName := w.sheetName(invoiceSheet, format.profile.InvoiceSheet)
+	productName := w.sheetName(productSheet, format.profile.ProductSheet)
+	if err := f.SetSheetName("Sheet1", templateInstructions); err != nil {
+		return nil, err
+	}
+	paymentTypes, err := w.writeInstructions(invoiceName, productName, active)
+	if err != nil {
+		return nil, err
+	}
+
+	cash, credit := examplePaymentTypes(active)
+	invoices := make([]map[string]any, len(templateInvoices))
+	for i, example := range templateInvoices {
+		invoices[i] = map[string]any{"payment_type": cash}
+		for field, value := range example {
+			invoices[i][field] = value
+		}
+	}
+	invoices[1]["payment_type"] = credit
+	if err := w.writeSheet(invoiceSheet, invoiceName, invoices); err != nil {
+		return nil, err
+	}
+	if err := w.writeSheet(productSheet, productName, templateProducts); err != nil {
+		return nil, err
+	}
+
+	// Dropdowns on the invoice sheet
+	if paymentTypes != "" {
+		dv := templateDropdown("payment_type")
+		dv.SetSqrefDropList(paymentTypes)
+		dv.SetError(excelize.DataValidationErrorStyleStop, "Payment type", "Pick a payment type from the list.")
+		if err := f.AddDataValidation(invoiceName, dv); err != nil {
+			return nil, err
+		}
+	}
+	dv := templateDropdown("tax_mode")
+	if err := dv.SetDropList([]string{models.TaxModeExclusive, models.TaxModeInclusive}); err != nil {
+		return nil, err
+	}
+	if err := f.AddDataValidation(invoiceName, dv); err != nil {
+		return nil, err
+	}
+
+	if index, err := f.GetSheetIndex(invoiceName); err == nil {
+		f.SetActiveSheet(index)
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// templateDropdown returns a data validation of the rows of a column of the invoice sheet under its header.
+func templateDropdown(field string) *excelize.DataValidation {
+	dv := excelize.NewDataValidation(true)
+	for i, column := range invoiceSheet.Columns {
+		if column.Field == field {
+			dv.SetSqref(fmt.Sprintf("%s2:%s%d", columnName(i), columnName(i), excelize.TotalRows))
+		}
+	}
+	return dv
+}
+
+// examplePaymentTypes picks the payment types of the example invoices: a cash method for the first,
+// and a credit method, if any, for the second, which has a due date.
+func examplePaymentTypes(paymentMethods []models.PaymentMethod) (cash, credit string) {
+	for _, paymentMethod := range paymentMethods {
+		if paymentMethod.IsCredit && credit == "" {
+			credit = paymentMethod.Code
+		}
+		if !paymentMethod.IsCredit && cash == "" {
+			cash = paymentMethod.Code
+		}
+	}
+	if cash == "" {
+		cash = credit
+	}
+	if credit == "" {
+		credit = cash
+	}
+	return cash, credit
+}
+
+// templateWriter writes the sheets of an import template.
+type templateWriter struct {
+	f            *excelize.File
+	format       importFormat
+	header, date int // Styles of the header rows and the date columns
+}
+
+// sheetName returns the name of an import sheet in the template, the profile's name for it if set.
+func (w *templateWriter) sheetName(sheet importSheet, profileName string) string {
+	if profileName != "" {
+		return profileName
+	}
+	return sheet.Name
+}
+
+// headerOf returns the header of a column in the template, the profile's header for it if set.
+func (w *templateWriter) headerOf(column importColumn) string {
+	if header, ok := w.format.profile.Columns[column.Field]; ok {
+		return header
+	}
+	return column.Header
+}
+
+// writeSheet adds an import sheet with the header of each of its columns and the example rows.
+func (w *templateWriter) writeSheet(sheet importSheet, name string, examples []map[string]any) error {
+	if _, err := w.f.NewSheet(name); err != nil {
+		return err
+	}
+	for i, column := range sheet.Columns {
+		colName := columnName(i)
+		width := max(len(w.headerOf(column))+4, 14)
+		if column.Date {
+			if err := w.f.SetColStyle(name, colName, w.date); err != nil {
+				return err
+			}
+		}
+		w.f.SetColWidth(name, colName, colName, float64(width))
+		w.f.SetCellStr(name, colName+"1", w.headerOf(column))
+		for row, example := range examples {
+			if value, ok := example[column.Field]; ok {
+				if err := w.f.SetCellValue(name, fmt.Sprintf("%s%d", colName, row+2), value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	w.f.SetCellStyle(name, "A1", columnName(len(sheet.Columns)-1)+"1", w.header)
+	return w.f.SetPanes(name, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// writeInstructions fills in the instructions sheet: how to fill in the template, each column of the
+// import sheets, and the payment types. It returns the range of the payment type codes, which the
+// dropdown of the invoice sheet lists, or "" if there are none.
+func (w *templateWriter) writeInstructions(invoiceName, productName string, paymentMethods []models.PaymentMethod) (string, error) {
+	f, name := w.f, templateInstructions
+	lines := []string{
+		"Invoice import template",
+		fmt.Sprintf("Fill in one row per invoice in the %q sheet, and one row per product line in the %q sheet.", invoiceName, productName),
+		"Product rows are linked to their invoice by the invoice number.",
+		"Required columns must be filled in on every row. Replace the example rows with your own before importing.",
+		"Dates may be date cells, or text in one of the formats " + w.format.dateFormatList() + ".",
+		"Upload the filled in workbook to POST /api/import. Use dry_run=true to check it first.",
+	}
+	for i, line := range lines {
+		f.SetCellStr(name, fmt.Sprintf("A%d", i+1), line)
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return "", err
+	}
+	f.SetCellStyle(name, "A1", "A1", bold)
+
+	row := len(lines) + 2
+	f.SetSheetRow(name, fmt.Sprintf("A%d", row), &[]string{"sheet", "column", "required", "description"})
+	f.SetCellStyle(name, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), w.header)
+	for _, sheet := range []struct {
+		importSheet
+		name string
+	}{{invoiceSheet, invoiceName}, {productSheet, productName}} {
+		for _, column := range sheet.Columns {
+			row++
+			required := ""
+			if column.Required {
+				required = "yes"
+			}
+			f.SetSheetRow(name, fmt.Sprintf("A%d", row), &[]string{sheet.name, w.headerOf(column), required, column.Help})
+		}
+	}
+
+	row += 2
+	f.SetSheetRow(name, fmt.Sprintf("A%d", row), &[]string{"payment type", "name"})
+	f.SetCellStyle(name, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), w.header)
+	first := row + 1
+	for _, paymentMethod := range paymentMethods {
+		row++
+		f.SetSheetRow(name, fmt.Sprintf("A%d", row), &[]string{paymentMethod.Code, paymentMethod.Name})
+	}
+
+	f.SetColWidth(name, "A", "A", 18)
+	f.SetColWidth(name, "B", "B", 22)
+	f.SetColWidth(name, "C", "C", 10)
+	f.SetColWidth(name, "D", "D", 90)
+	if len(paymentMethods) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("'%s'!$A$%d:$A$%d", name, first, row), nil
+}
@@ -16,22 +16,37 @@ func NewInvoiceService(db *sql.DB) *InvoiceService {
 	return &InvoiceService{DB: db}
 }
 
-// CreateInvoice creates a new invoice
-func (is *InvoiceService) CreateInvoice(invoiceData models.Invoice) error {
+// CreateInvoice creates a new invoice, numbering it if no invoice number was given, and returns any warnings
+func (is *InvoiceService) CreateInvoice(invoiceData *models.Invoice) ([]models.Issue, error) {
 	return repository.CreateInvoice(is.DB, invoiceData)
 }
 
 // GetInvoices retrieves a list of invoices
-func (is *InvoiceService) GetInvoices(payload models.InvoiceRequest) ([]models.Invoice, float64, float64, error) {
+func (is *InvoiceService) GetInvoices(payload models.InvoiceRequest) ([]models.Invoice, models.InvoiceTotals, error) {
 	return repository.GetInvoices(is.DB, payload)
 }
 
-// UpdateInvoice updates an existing invoice
-func (is *InvoiceService) UpdateInvoice(invoiceData models.UpdateInvoiceRequest) error {
-	return repository.UpdateInvoice(is.DB, invoiceData)
+// UpdateInvoice updates an existing invoice and returns it as stored, along with any warnings
+func (is *InvoiceService) UpdateInvoice(invoiceData models.UpdateInvoiceRequest) (models.Invoice, []models.Issue, error) {
+	warnings, err := repository.UpdateInvoice(is.DB, invoiceData)
+	if err != nil {
+		return models.Invoice{}, nil, err
+	}
+	invoice, err := repository.GetInvoice(is.DB, invoiceData.InvoiceNo)
+	return invoice, warnings, err
 }
 
 // DeleteInvoice deletes an invoice by its invoice number
 func (is *InvoiceService) DeleteInvoice(invoiceNo string) error {
 	return repository.DeleteInvoice(is.DB, invoiceNo)
 }
+
+// IssueInvoice issues a draft invoice
+func (is *InvoiceService) IssueInvoice(invoiceNo string) error {
+	return repository.IssueInvoice(is.DB, invoiceNo)
+}
+
+// VoidInvoice voids an invoice with a reason
+func (is *InvoiceService) VoidInvoice(invoiceNo, reason string) error {
+	return repository.VoidInvoice(is.DB, invoiceNo, reason)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// NumberSequenceService defines the service layer for number sequence operations
+type NumberSequenceService struct {
+	DB *sql.DB
+}
+
+// NewNumberSequenceService creates a new NumberSequenceService instance
+func NewNumberSequenceService(db *sql.DB) *NumberSequenceService {
+	return &NumberSequenceService{DB: db}
+}
+
+// GetNumberSequences retrieves all number sequences
+func (ns *NumberSequenceService) GetNumberSequences() ([]models.NumberSequence, error) {
+	return repository.GetNumberSequences(ns.DB)
+}
+
+// GetNumberSequence retrieves a number sequence by name
+func (ns *NumberSequenceService) GetNumberSequence(name string) (models.NumberSequence, error) {
+	return repository.GetNumberSequence(ns.DB, name)
+}
+
+// UpdateNumberSequence changes the pattern of a number sequence
+func (ns *NumberSequenceService) UpdateNumberSequence(sequence *models.NumberSequence) error {
+	return repository.UpdateNumberSequence(ns.DB, sequence)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// PaymentMethodService defines the service layer for payment method operations
+type PaymentMethodService struct {
+	DB *sql.DB
+}
+
+// NewPaymentMethodService creates a new PaymentMethodService instance
+func NewPaymentMethodService(db *sql.DB) *PaymentMethodService {
+	return &PaymentMethodService{DB: db}
+}
+
+// GetPaymentMethods retrieves all payment methods
+func (ps *PaymentMethodService) GetPaymentMethods() ([]models.PaymentMethod, error) {
+	return repository.GetPaymentMethods(ps.DB)
+}
+
+// GetPaymentMethod retrieves a payment method by its code
+func (ps *PaymentMethodService) GetPaymentMethod(code string) (models.PaymentMethod, error) {
+	return repository.GetPaymentMethod(ps.DB, code)
+}
+
+// CreatePaymentMethod creates a new payment method
+func (ps *PaymentMethodService) CreatePaymentMethod(paymentMethod models.PaymentMethod) error {
+	return repository.CreatePaymentMethod(ps.DB, paymentMethod)
+}
+
+// UpdatePaymentMethod updates an existing payment method
+func (ps *PaymentMethodService) UpdatePaymentMethod(paymentMethod models.PaymentMethod) error {
+	return repository.UpdatePaymentMethod(ps.DB, paymentMethod)
+}
+
+// DeletePaymentMethod deletes a payment method by its code
+func (ps *PaymentMethodService) DeletePaymentMethod(code string) error {
+	return repository.DeletePaymentMethod(ps.DB, code)
+}
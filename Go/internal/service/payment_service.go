@@ -0,0 +1,37 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// PaymentService defines the service layer for payments on credit invoices
+type PaymentService struct {
+	DB *sql.DB
+}
+
+// NewPaymentService creates a new PaymentService instance
+func NewPaymentService(db *sql.DB) *PaymentService {
+	return &PaymentService{DB: db}
+}
+
+// GetPayments retrieves the payments and the balance of an invoice
+func (ps *PaymentService) GetPayments(invoiceNo string) ([]models.Payment, models.InvoiceBalance, error) {
+	balance, err := repository.GetInvoiceBalance(ps.DB, invoiceNo)
+	if err != nil {
+		return nil, balance, err
+	}
+	payments, err := repository.GetPayments(ps.DB, invoiceNo)
+	return payments, balance, err
+}
+
+// RecordPayment records a payment on an invoice and returns the updated balance
+func (ps *PaymentService) RecordPayment(payment *models.Payment) (models.InvoiceBalance, error) {
+	return repository.CreatePayment(ps.DB, payment)
+}
+
+// DeletePayment deletes a payment from an invoice
+func (ps *PaymentService) DeletePayment(invoiceNo string, id int) error {
+	return repository.DeletePayment(ps.DB, invoiceNo, id)
+}
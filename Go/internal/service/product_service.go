@@ -0,0 +1,32 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// ProductService defines the service layer for the products of draft invoices
+type ProductService struct {
+	DB *sql.DB
+}
+
+// NewProductService creates a new ProductService instance
+func NewProductService(db *sql.DB) *ProductService {
+	return &ProductService{DB: db}
+}
+
+// AddProduct adds a product to a draft invoice and returns any warnings
+func (ps *ProductService) AddProduct(invoiceNo string, product *models.Product) ([]models.Issue, error) {
+	return repository.AddProduct(ps.DB, invoiceNo, product)
+}
+
+// UpdateProduct updates a product of a draft invoice and returns any warnings
+func (ps *ProductService) UpdateProduct(invoiceNo string, product *models.Product) ([]models.Issue, error) {
+	return repository.UpdateProduct(ps.DB, invoiceNo, product)
+}
+
+// DeleteProduct deletes a product from a draft invoice
+func (ps *ProductService) DeleteProduct(invoiceNo string, id int) error {
+	return repository.DeleteProduct(ps.DB, invoiceNo, id)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+	"widatech-technical-challenge/utils"
+)
+
+// ReportService defines the service layer for reporting
+type ReportService struct {
+	DB *sql.DB
+}
+
+// NewReportService creates a new ReportService instance
+func NewReportService(db *sql.DB) *ReportService {
+	return &ReportService{DB: db}
+}
+
+// GetSalesReport totals the invoices and credit notes dated between from and to in the base currency
+func (rs *ReportService) GetSalesReport(from, to time.Time) (models.SalesReport, error) {
+	report := models.SalesReport{From: from, To: to}
+
+	invoices, err := repository.GetInvoicesByDateRange(rs.DB, from, to)
+	if err != nil {
+		return report, err
+	}
+	creditNotes, err := repository.GetCreditNotesByDateRange(rs.DB, from, to)
+	if err != nil {
+		return report, err
+	}
+	report.InvoiceTotals, err = repository.SummarizeInvoices(rs.DB, invoices, creditNotes, utils.BaseCurrency())
+	return report, err
+}
+
+// GetAgingReport buckets the outstanding balances of credit invoices by days past due on asOf,
+// converted into the base currency at the rate on each invoice date
+func (rs *ReportService) GetAgingReport(asOf time.Time) (models.AgingReport, error) {
+	report := models.AgingReport{AsOf: asOf, BaseCurrency: utils.BaseCurrency(), Customers: []models.CustomerAging{}}
+
+	invoices, err := repository.GetOpenCreditInvoices(rs.DB, asOf)
+	if err != nil {
+		return report, err
+	}
+
+	converter := repository.NewConverter(rs.DB, report.BaseCurrency)
+	for i := range invoices {
+		invoice := &invoices[i]
+		rate, err := converter.Rate(invoice.Currency, invoice.Date)
+		if err != nil {
+			return report, err
+		}
+		invoice.BaseOutstanding = invoice.OutstandingAmount.Convert(rate)
+		report.Totals.Add(invoice.BaseOutstanding, invoice.DaysPastDue)
+
+		// Invoices are ordered by customer, so each customer's invoices are adjacent
+		if n := len(report.Customers); n == 0 || report.Customers[n-1].CustomerName != invoice.CustomerName {
+			report.Customers = append(report.Customers, models.CustomerAging{CustomerName: invoice.CustomerName})
+		}
+		report.Customers[len(report.Customers)-1].Add(invoice.BaseOutstanding, invoice.DaysPastDue)
+	}
+	report.Invoices = invoices
+	return report, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"database/sql"
+	"widatech-technical-challenge/internal/models"
+	"widatech-technical-challenge/internal/repository"
+)
+
+// TaxRateService defines the service layer for tax rate operations
+type TaxRateService struct {
+	DB *sql.DB
+}
+
+// NewTaxRateService creates a new TaxRateService instance
+func NewTaxRateService(db *sql.DB) *TaxRateService {
+	return &TaxRateService{DB: db}
+}
+
+// GetTaxRates retrieves all tax rates
+func (ts *TaxRateService) GetTaxRates() ([]models.TaxRate, error) {
+	return repository.GetTaxRates(ts.DB)
+}
+
+// GetTaxRate retrieves a tax rate by its code
+func (ts *TaxRateService) GetTaxRate(code string) (models.TaxRate, error) {
+	return repository.GetTaxRate(ts.DB, code)
+}
+
+// CreateTaxRate creates a new tax rate
+func (ts *TaxRateService) CreateTaxRate(taxRate models.TaxRate) error {
+	return repository.CreateTaxRate(ts.DB, taxRate)
+}
+
+// UpdateTaxRate updates an existing tax rate
+func (ts *TaxRateService) UpdateTaxRate(taxRate models.TaxRate) error {
+	return repository.UpdateTaxRate(ts.DB, taxRate)
+}
+
+// DeleteTaxRate deletes a tax rate by its code
+func (ts *TaxRateService) DeleteTaxRate(code string) error {
+	return repository.DeleteTaxRate(ts.DB, code)
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"widatech-technical-challenge/internal/models"
+)
+
+// ProrateCreditNoteLine checks the returned quantity against what is left of the original
+// product line and credits the matching share of its cost, price and tax. credited holds
+// what earlier credit notes already took off the line. Returning the rest of a line credits
+// exactly what is left, so a line returned in parts never ends up off by a cent.
+func ProrateCreditNoteLine(line *models.CreditNoteLine, product models.Product, credited models.CreditNoteLine) error {
+	remaining := product.Quantity - credited.Quantity
+	if line.Quantity < 1 {
+		return fmt.Errorf("quantity of product %d must be at least 1", line.ProductID)
+	}
+	if line.Quantity > remaining {
+		return fmt.Errorf("quantity %d of product %d exceeds the %d left to credit", line.Quantity, line.ProductID, remaining)
+	}
+
+	line.ItemName = product.ItemName
+	line.TaxCode = product.TaxCode
+	line.TaxRate = product.TaxRate
+	if line.Quantity == remaining {
+		line.TotalCost = product.TotalCost - credited.TotalCost
+		line.TotalPrice = product.TotalPrice - credited.TotalPrice
+		line.TaxAmount = product.TaxAmount - credited.TaxAmount
+		return nil
+	}
+
+	quantity, sold := int64(line.Quantity), int64(product.Quantity)
+	line.TotalCost = product.TotalCost.MulRatio(quantity, sold)
+	line.TotalPrice = product.TotalPrice.MulRatio(quantity, sold)
+	line.TaxAmount = product.TaxAmount.MulRatio(quantity, sold)
+	return nil
+}
+
+// CreditNoteTotalAmount returns what a credit note gives back to the customer: its line totals,
+// plus the tax when the invoice prices exclude it.
+func CreditNoteTotalAmount(creditNote models.CreditNote) models.Money {
+	var total models.Money
+	for _, line := range creditNote.Lines {
+		total += line.TotalPrice
+	}
+	if creditNote.TaxMode != models.TaxModeInclusive {
+		total += creditNote.TaxTotal
+	}
+	return total
+}
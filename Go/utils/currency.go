@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"widatech-technical-challenge/internal/models"
+)
+
+// defaultBaseCurrency is used when BASE_CURRENCY is not set.
+const defaultBaseCurrency = "IDR"
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// BaseCurrency returns the currency reports are converted into, from the BASE_CURRENCY environment variable.
+func BaseCurrency() string {
+	if currency := os.Getenv("BASE_CURRENCY"); currency != "" {
+		return currency
+	}
+	return defaultBaseCurrency
+}
+
+// ValidateCurrency ensures the currency is a three-letter upper-case ISO 4217 code.
+func ValidateCurrency(currency string) error {
+	if !currencyCodePattern.MatchString(currency) {
+		return errors.New("invalid currency: must be a 3-letter ISO 4217 code such as 'IDR' or 'USD'")
+	}
+	return nil
+}
+
+// ValidateExchangeRate checks the fields of an exchange rate before it is stored.
+func ValidateExchangeRate(exchangeRate models.ExchangeRate) error {
+	if err := ValidateCurrency(exchangeRate.Currency); err != nil {
+		return err
+	}
+	if exchangeRate.Currency == exchangeRate.BaseCurrency {
+		return errors.New("currency must differ from the base currency")
+	}
+	if exchangeRate.RateDate.IsZero() {
+		return errors.New("rate_date is required")
+	}
+	if exchangeRate.Rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+	return nil
+}
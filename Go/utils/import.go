@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"widatech-technical-challenge/internal/models"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// NormalizeHeader folds a sheet name or column header for matching: lower case, with underscores,
+// dashes and runs of whitespace turned into single spaces, so "Invoice_No" matches "invoice  no".
+func NormalizeHeader(header string) string {
+	header = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(header))
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// ImportHeaderSynonyms reads extra column headers accepted by the import from IMPORT_HEADER_SYNONYMS,
+// a semicolon-separated list of field=header|header entries, e.g. "customer_name=client|buyer;quantity=pcs".
+func ImportHeaderSynonyms() map[string][]string {
+	synonyms := map[string][]string{}
+	for _, entry := range strings.Split(os.Getenv("IMPORT_HEADER_SYNONYMS"), ";") {
+		field, headers, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		field = strings.TrimSpace(strings.ToLower(field))
+		for _, header := range strings.Split(headers, "|") {
+			if header = NormalizeHeader(header); header != "" {
+				synonyms[field] = append(synonyms[field], header)
+			}
+		}
+	}
+	return synonyms
+}
+
+// defaultImportBatchSize is used when IMPORT_BATCH_SIZE is not set.
+const defaultImportBatchSize = 500
+
+// ImportBatchSize returns how many invoices a partial import commits at a time, from the
+// IMPORT_BATCH_SIZE environment variable.
+func ImportBatchSize() int {
+	if n, err := strconv.Atoi(os.Getenv("IMPORT_BATCH_SIZE")); err == nil && n > 0 {
+		return n
+	}
+	return defaultImportBatchSize
+}
+
+// defaultImportResultLimit is used when IMPORT_RESULT_LIMIT is not set.
+const defaultImportResultLimit = 1000
+
+// ImportResultLimit returns how many rows, errors and warnings the result of an import lists at most,
+// from the IMPORT_RESULT_LIMIT environment variable. The rest are counted but not listed.
+func ImportResultLimit() int {
+	if n, err := strconv.Atoi(os.Getenv("IMPORT_RESULT_LIMIT")); err == nil && n > 0 {
+		return n
+	}
+	return defaultImportResultLimit
+}
+
+// defaultImportWorkers is used when IMPORT_WORKERS is not set.
+const defaultImportWorkers = 2
+
+// ImportWorkers returns how many import jobs run at the same time, from the IMPORT_WORKERS environment variable.
+func ImportWorkers() int {
+	if n, err := strconv.Atoi(os.Getenv("IMPORT_WORKERS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultImportWorkers
+}
+
+// InstanceID returns the name this server goes by among the servers sharing the database, from the
+// INSTANCE_ID environment variable, defaulting to the host name. Each server must have its own: the
+// import jobs and staged rows a server owns are cleaned up when it starts again with the same name.
+func InstanceID() string {
+	if id := strings.TrimSpace(os.Getenv("INSTANCE_ID")); id != "" {
+		return id
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "localhost"
+}
+
+// groupedNumbers match numbers written with a thousands separator, for each decimal separator.
+var groupedNumbers = map[string]*regexp.Regexp{
+	".": regexp.MustCompile(`^-?\d{1,3}(,\d{3})+(\.\d+)?$`),
+	",": regexp.MustCompile(`^-?\d{1,3}(\.\d{3})+(,\d+)?$`),
+}
+
+// NormalizeDecimal rewrites a number written with the given decimal separator, and optionally the other
+// one as a thousands separator, in the plain form the money parser reads, e.g. "1.234,5" with "," as
+// "1234.5". A thousands separator anywhere but between groups of three digits is an error.
+func NormalizeDecimal(value, separator string) (string, error) {
+	thousands := ","
+	if separator == "," {
+		thousands = "."
+	}
+	if pattern := groupedNumbers[separator]; pattern != nil && pattern.MatchString(value) {
+		value = strings.ReplaceAll(value, thousands, "")
+	}
+	if strings.Contains(value, thousands) {
+		return "", fmt.Errorf("%q is not a number with %q as the decimal separator", value, separator)
+	}
+	return strings.Replace(value, separator, ".", 1), nil
+}
+
+// ValidateImportProfile checks the settings of an import profile before it is stored.
+// fields are the fields the import reads, which the column and default maps may name.
+func ValidateImportProfile(profile models.ImportProfile, fields []string) error {
+	if !importProfileNamePattern.MatchString(profile.Name) {
+		return errors.New("name must be lower case letters, digits, '-' or '_', starting with a letter")
+	}
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field] = true
+	}
+	for field, header := range profile.Columns {
+		if !known[field] {
+			return fmt.Errorf("columns: unknown field %q", field)
+		}
+		if NormalizeHeader(header) == "" {
+			return fmt.Errorf("columns: header of %s is empty", field)
+		}
+	}
+	for field := range profile.Defaults {
+		if !known[field] {
+			return fmt.Errorf("defaults: unknown field %q", field)
+		}
+		if field == "invoice_no" {
+			return errors.New("defaults: invoice_no links the sheets and can't have a default")
+		}
+	}
+	if profile.DateFormat != "" {
+		if _, err := ParseDateFormats(profile.DateFormat); err != nil {
+			return err
+		}
+	}
+	if profile.DecimalSeparator != "." && profile.DecimalSeparator != "," {
+		return errors.New(`decimal_separator must be "." or ","`)
+	}
+	return nil
+}
+
+// importProfileNamePattern is the form of profile names, which are used in the URL.
+var importProfileNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// DetectImportFormat sniffs the format of an uploaded file from its first bytes: XLSX workbooks are
+// zip archives, and CSV files are text, in UTF-16 with a byte order mark or else in UTF-8 or
+// Windows-1252 as Excel saves them. It returns "" for anything else, such as legacy XLS files.
+// Whether a zip archive really is a workbook is only known once it is opened.
+func DetectImportFormat(head []byte) string {
+	switch http.DetectContentType(head) {
+	case "application/zip":
+		return models.ImportFormatXLSX
+	case "text/plain; charset=utf-8", "text/plain; charset=utf-16le", "text/plain; charset=utf-16be":
+		return models.ImportFormatCSV
+	}
+	return ""
+}
+
+// DecodeCSV reads a CSV file as UTF-8 text. A file with a UTF-16 byte order mark is decoded from UTF-16,
+// and a UTF-8 byte order mark is dropped. Otherwise valid UTF-8 is kept and any other byte is read as
+// Windows-1252, the encoding Excel saves CSV files in on western Windows systems.
+func DecodeCSV(file io.Reader) io.Reader {
+	return transform.NewReader(file, unicode.BOMOverride(windows1252Fallback{}))
+}
+
+// windows1252Fallback passes UTF-8 through and decodes the bytes that aren't valid UTF-8 as Windows-1252.
+type windows1252Fallback struct{ transform.NopResetter }
+
+func (windows1252Fallback) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc // The rest of the character is in the next chunk
+			}
+			r = charmap.Windows1252.DecodeByte(src[nSrc])
+		}
+		if nDst+utf8.RuneLen(r) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += utf8.EncodeRune(dst[nDst:], r)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// CSVDelimiter picks the delimiter of a CSV file from its header line: a comma, or a semicolon or tab
+// if the line has more of those, as spreadsheets in locales with a decimal comma write.
+func CSVDelimiter(header string) rune {
+	delimiter, most := ',', strings.Count(header, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(candidate)); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultImportDateFormats are the date formats the import reads when IMPORT_DATE_FORMATS is not set.
+const defaultImportDateFormats = "DD-MM-YY,DD-MM-YYYY,DD/MM/YY,DD/MM/YYYY,YYYY-MM-DD"
+
+// ErrAmbiguousDate is returned when a date matches several formats that read it as different days.
+var ErrAmbiguousDate = errors.New("ambiguous date")
+
+// DateFormat is a date format the import reads, such as DD/MM/YYYY.
+type DateFormat struct {
+	Format       string // As written, e.g. DD/MM/YYYY
+	Layout       string // Go time layout
+	TwoDigitYear bool   // The year is written as YY
+}
+
+// dateFormatTokens maps the tokens of a date format onto Go layout elements, longest first.
+// Days and months may be written with one or two digits.
+var dateFormatTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"YY", "06"}, {"MM", "1"}, {"DD", "2"},
+}
+
+// ParseDateFormat converts a date format such as DD/MM/YYYY into a Go time layout. The format needs
+// a day (DD), a month (MM) and a year (YYYY or YY), separated by characters other than letters and digits.
+func ParseDateFormat(format string) (DateFormat, error) {
+	var layout strings.Builder
+	seen := map[string]bool{}
+	dateFormat := DateFormat{Format: strings.TrimSpace(format)}
+	for rest := strings.ToUpper(dateFormat.Format); rest != ""; {
+		matched := false
+		for _, t := range dateFormatTokens {
+			if strings.HasPrefix(rest, t.token) {
+				layout.WriteString(t.layout)
+				seen[t.token[:2]] = true
+				dateFormat.TwoDigitYear = dateFormat.TwoDigitYear || t.token == "YY"
+				rest = rest[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if c := rest[0]; c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			return DateFormat{}, fmt.Errorf("date format %q may only contain DD, MM, YYYY or YY and separators", format)
+		}
+		layout.WriteByte(rest[0])
+		rest = rest[1:]
+	}
+	if !seen["DD"] || !seen["MM"] || !seen["YY"] {
+		return DateFormat{}, fmt.Errorf("date format %q needs DD, MM and YYYY or YY", format)
+	}
+	dateFormat.Layout = layout.String()
+	return dateFormat, nil
+}
+
+// ParseDateFormats parses a comma-separated list of date formats.
+func ParseDateFormats(formats string) ([]DateFormat, error) {
+	var dateFormats []DateFormat
+	for _, format := range strings.Split(formats, ",") {
+		if strings.TrimSpace(format) == "" {
+			continue
+		}
+		dateFormat, err := ParseDateFormat(format)
+		if err != nil {
+			return nil, err
+		}
+		dateFormats = append(dateFormats, dateFormat)
+	}
+	if len(dateFormats) == 0 {
+		return nil, errors.New("at least one date format is required")
+	}
+	return dateFormats, nil
+}
+
+// ImportDateFormats returns the date formats the import reads, from IMPORT_DATE_FORMATS,
+// a comma-separated list such as "DD-MM-YY,YYYY-MM-DD".
+func ImportDateFormats() []DateFormat {
+	if formats, err := ParseDateFormats(os.Getenv("IMPORT_DATE_FORMATS")); err == nil {
+		return formats
+	}
+	formats, _ := ParseDateFormats(defaultImportDateFormats)
+	return formats
+}
+
+// ParseImportDate reads a date written in one of formats. Two-digit years are always read as 20YY.
+// A value that formats read as different days returns ErrAmbiguousDate rather than a guess.
+func ParseImportDate(value string, formats []DateFormat) (time.Time, error) {
+	var date time.Time
+	var matched []string
+	for _, format := range formats {
+		parsed, err := time.Parse(format.Layout, value)
+		if err != nil {
+			continue
+		}
+		if format.TwoDigitYear {
+			// Go reads 69-99 as 1969-1999; invoices are never that old
+			parsed = time.Date(2000+parsed.Year()%100, parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		if len(matched) > 0 && !parsed.Equal(date) {
+			return time.Time{}, fmt.Errorf("%w: %q reads as %s with %s but as %s with %s", ErrAmbiguousDate, value,
+				date.Format("2006-01-02"), matched[0], parsed.Format("2006-01-02"), format.Format)
+		}
+		date = parsed
+		matched = append(matched, format.Format)
+	}
+	if len(matched) == 0 {
+		return time.Time{}, fmt.Errorf("%q doesn't match any of the date formats", value)
+	}
+	return date, nil
+}
+
+// maxExcelSerial is the serial of 9999-12-31, the last date Excel supports.
+const maxExcelSerial = 2958465
+
+// ExcelSerialDate converts an Excel date serial, as stored in date cells, into a date. Workbooks count
+// days from 1900 (serial 1 is 1900-01-01) or, in the 1904 date system, from 1904 (serial 0 is 1904-01-01).
+// The time of day is dropped. In the 1900 system, serial 60 is 1900-02-29, a day that doesn't exist.
+func ExcelSerialDate(serial float64, date1904 bool) (time.Time, error) {
+	days := int(serial)
+	if date1904 {
+		if serial < 0 || days > maxExcelSerial-1462 {
+			return time.Time{}, fmt.Errorf("%v is not an Excel date", serial)
+		}
+		return time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days), nil
+	}
+	switch {
+	case serial < 1 || days > maxExcelSerial:
+		return time.Time{}, fmt.Errorf("%v is not an Excel date", serial)
+	case days == 60:
+		return time.Time{}, errors.New("serial 60 is 1900-02-29, which doesn't exist")
+	case days < 60:
+		return time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days), nil
+	}
+	// Excel counts the missing 1900-02-29, so later serials are a day ahead
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days), nil
+}
+
+// excelSerialPattern matches the raw value of a date cell: its serial, with the time of day as a fraction.
+var excelSerialPattern = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// ParseExcelSerial reports whether value is a number, which a date cell holds as its serial.
+func ParseExcelSerial(value string) (float64, bool) {
+	if !excelSerialPattern.MatchString(value) {
+		return 0, false
+	}
+	serial, err := strconv.ParseFloat(value, 64)
+	return serial, err == nil
+}
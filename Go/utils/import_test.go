@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"widatech-technical-challenge/internal/models"
+)
+
+func TestDecodeCSV(t *testing.T) {
+	const want = "invoice no;customer\nINV-001;Café Müller\n"
+	utf16le := []byte{0xff, 0xfe}
+	for _, r := range want {
+		utf16le = append(utf16le, byte(r), byte(r>>8))
+	}
+	utf16be := []byte{0xfe, 0xff}
+	for _, r := range want {
+		utf16be = append(utf16be, byte(r>>8), byte(r))
+	}
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"UTF-8", []byte(want)},
+		{"UTF-8 with a byte order mark", append([]byte("\xef\xbb\xbf"), want...)},
+		{"UTF-16LE", utf16le},
+		{"UTF-16BE", utf16be},
+		{"Windows-1252", []byte("invoice no;customer\nINV-001;Caf\xe9 M\xfcller\n")},
+	}
+	for _, tt := range tests {
+		if format := DetectImportFormat(tt.file); format != models.ImportFormatCSV {
+			t.Errorf("%s: DetectImportFormat = %q, want csv", tt.name, format)
+		}
+		decoded, err := io.ReadAll(DecodeCSV(strings.NewReader(string(tt.file))))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if string(decoded) != want {
+			t.Errorf("%s: decoded %q, want %q", tt.name, decoded, want)
+		}
+	}
+
+	// A character split across reads is decoded whole, not as Windows-1252
+	decoded, err := io.ReadAll(DecodeCSV(io.MultiReader(strings.NewReader("Caf\xc3"), strings.NewReader("\xa9"))))
+	if err != nil || string(decoded) != "Café" {
+		t.Errorf("split character: decoded %q, %v, want \"Café\"", decoded, err)
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+// defaultPaymentTermsDays is used when DEFAULT_PAYMENT_TERMS_DAYS is not set.
+const defaultPaymentTermsDays = 30
+
+// DefaultPaymentTermsDays returns the terms given to credit invoices created without a due date,
+// from the DEFAULT_PAYMENT_TERMS_DAYS environment variable.
+func DefaultPaymentTermsDays() int {
+	if days, err := strconv.Atoi(os.Getenv("DEFAULT_PAYMENT_TERMS_DAYS")); err == nil && days >= 0 {
+		return days
+	}
+	return defaultPaymentTermsDays
+}
+
+// PaymentStatus computes the status of an invoice from its total, the amount paid so far and its due date.
+// Invoices on a non-credit payment method are settled when they are created.
+func PaymentStatus(total, paid models.Money, dueDate *time.Time, isCredit bool, today time.Time) string {
+	switch {
+	case !isCredit || paid >= total:
+		return models.PaymentStatusPaid
+	case dueDate != nil && DaysBetween(*dueDate, today) > 0:
+		return models.PaymentStatusOverdue
+	case paid > 0:
+		return models.PaymentStatusPartial
+	}
+	return models.PaymentStatusUnpaid
+}
+
+// DaysBetween returns the number of calendar days from one date to another, ignoring the time of day.
+func DaysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// ValidatePayment checks a payment against the invoice balance it is recorded on.
+func ValidatePayment(payment models.Payment, balance models.InvoiceBalance, invoiceDate time.Time) error {
+	if payment.Amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+	if payment.PaidAt.IsZero() {
+		return errors.New("paid_at is required")
+	}
+	if DaysBetween(invoiceDate, payment.PaidAt) < 0 {
+		return errors.New("paid_at cannot be before the invoice date")
+	}
+	if payment.Amount > balance.OutstandingAmount {
+		return errors.New("amount exceeds the outstanding balance of " + balance.OutstandingAmount.String())
+	}
+	return nil
+}
+
+// InvoiceTotalAmount returns what the customer owes for the invoice: its line totals,
+// plus the tax when prices exclude it.
+func InvoiceTotalAmount(invoice models.Invoice) models.Money {
+	var total models.Money
+	for _, product := range invoice.Products {
+		total += product.TotalPrice
+	}
+	if invoice.TaxMode != models.TaxModeInclusive {
+		total += invoice.TaxTotal
+	}
+	return total
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"widatech-technical-challenge/internal/models"
+)
+
+// priceTolerance is the slack allowed between a line total and quantity × unit value.
+// Unit values are stored to the cent, so each unit may be off by half a cent.
+func priceTolerance(quantity int) models.Money {
+	if quantity < 2 {
+		return 1
+	}
+	return models.Money((quantity + 1) / 2)
+}
+
+// absMoney returns the absolute value of an amount.
+func absMoney(m models.Money) models.Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// ResolveProductPricing derives whichever of the unit or total values were left out,
+// so clients can send either side. Values that were supplied are never overwritten;
+// ValidateProduct reports them if they disagree.
+func ResolveProductPricing(product *models.Product) {
+	if product.Quantity < 1 {
+		return
+	}
+	quantity := int64(product.Quantity)
+
+	if product.UnitCost == 0 && product.TotalCost > 0 {
+		product.UnitCost = product.TotalCost.Div(quantity)
+	}
+	if product.TotalCost == 0 && product.UnitCost > 0 {
+		product.TotalCost = product.UnitCost.Mul(quantity)
+	}
+
+	// Work back from the total to the undiscounted unit price.
+	if product.UnitPrice == 0 && product.TotalPrice > 0 {
+		gross := product.TotalPrice.Add(product.DiscountAmount)
+		if product.DiscountAmount == 0 && product.DiscountPercent > 0 && product.DiscountPercent < 10000 {
+			gross = product.TotalPrice.MulRatio(10000, 10000-int64(product.DiscountPercent))
+		}
+		product.UnitPrice = gross.Div(quantity)
+	}
+
+	gross := product.UnitPrice.Mul(quantity)
+	if product.DiscountAmount == 0 && product.DiscountPercent > 0 {
+		product.DiscountAmount = gross.Percent(product.DiscountPercent)
+	}
+	if product.TotalPrice == 0 && product.UnitPrice > 0 {
+		product.TotalPrice = gross.Sub(product.DiscountAmount)
+	}
+}
+
+// validateDiscountPercent checks that a discount percent, stored in hundredths, is between 0 and 100.
+func validateDiscountPercent(product models.Product) error {
+	if product.DiscountPercent < 0 || product.DiscountPercent > 10000 {
+		return errors.New("discount_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// pricedWithin reports whether the unit values and discount of a line are in range, so the checks that
+// they agree with the totals are worth making. Values out of range are reported by rules of their own.
+func pricedWithin(product models.Product) bool {
+	return product.UnitCost >= 0 && product.UnitPrice >= 0 && product.DiscountAmount >= 0 && validateDiscountPercent(product) == nil
+}
+
+// validateProductDiscount checks that the discount of a line fits quantity × unit_price and agrees
+// with its discount_percent.
+func validateProductDiscount(product models.Product) error {
+	if !pricedWithin(product) {
+		return nil
+	}
+	tolerance := priceTolerance(product.Quantity)
+	gross := product.UnitPrice.Mul(int64(product.Quantity))
+	if product.DiscountAmount > gross.Add(tolerance) {
+		return errors.New("discount_amount cannot exceed quantity × unit_price")
+	}
+	if product.DiscountPercent > 0 && absMoney(product.DiscountAmount.Sub(gross.Percent(product.DiscountPercent))) > tolerance {
+		return errors.New("discount_amount does not match discount_percent of quantity × unit_price")
+	}
+	return nil
+}
+
+// validateProductCost checks that the total cost of a line is quantity × unit_cost.
+func validateProductCost(product models.Product) error {
+	if !pricedWithin(product) {
+		return nil
+	}
+	if absMoney(product.UnitCost.Mul(int64(product.Quantity)).Sub(product.TotalCost)) > priceTolerance(product.Quantity) {
+		return errors.New("total_cost must equal quantity × unit_cost")
+	}
+	return nil
+}
+
+// validateProductPrice checks that the total price of a line is quantity × unit_price less the discount.
+func validateProductPrice(product models.Product) error {
+	if !pricedWithin(product) {
+		return nil
+	}
+	gross := product.UnitPrice.Mul(int64(product.Quantity))
+	if absMoney(gross.Sub(product.DiscountAmount).Sub(product.TotalPrice)) > priceTolerance(product.Quantity) {
+		return errors.New("total_price must equal quantity × unit_price − discount")
+	}
+	return nil
+}
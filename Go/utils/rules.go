@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"widatech-technical-challenge/internal/models"
+)
+
+// Rule is a single declarative check on a value of type T. Test returns an
+// explanation when the value breaks the rule and "" when it passes.
+type Rule[T any] struct {
+	Field    string
+	Name     string
+	Severity string
+	Test     func(v T) string
+}
+
+// RuleSet is an ordered list of rules evaluated together.
+type RuleSet[T any] []Rule[T]
+
+// Evaluate runs every rule against v and returns the issues found, in rule order.
+func (rs RuleSet[T]) Evaluate(v T) []models.Issue {
+	var issues []models.Issue
+	for _, rule := range rs {
+		if message := rule.Test(v); message != "" {
+			issues = append(issues, models.Issue{Field: rule.Field, Rule: rule.Name, Severity: rule.Severity, Message: message})
+		}
+	}
+	return issues
+}
+
+// MinLength requires a text field to have at least n characters, counted as PostgreSQL's LENGTH does.
+func MinLength[T any](field string, n int, get func(T) string) Rule[T] {
+	return Rule[T]{Field: field, Name: "min_length", Severity: models.SeverityError, Test: func(v T) string {
+		if utf8.RuneCountInString(get(v)) < n {
+			return fmt.Sprintf("%s must have at least %d %s", field, n, plural(n, "character"))
+		}
+		return ""
+	}}
+}
+
+// OptionalMinLength requires a text field to have at least n characters if it is provided.
+func OptionalMinLength[T any](field string, n int, get func(T) string) Rule[T] {
+	return Rule[T]{Field: field, Name: "min_length", Severity: models.SeverityError, Test: func(v T) string {
+		if value := get(v); value != "" && utf8.RuneCountInString(value) < n {
+			return fmt.Sprintf("%s must have at least %d %s if provided", field, n, plural(n, "character"))
+		}
+		return ""
+	}}
+}
+
+// MinInt requires a whole-number field to be at least n.
+func MinInt[T any](field string, n int, get func(T) int) Rule[T] {
+	return Rule[T]{Field: field, Name: "min", Severity: models.SeverityError, Test: func(v T) string {
+		if get(v) < n {
+			return fmt.Sprintf("%s must be at least %d", field, n)
+		}
+		return ""
+	}}
+}
+
+// NonNegative requires an amount to be zero or more.
+func NonNegative[T any](field string, get func(T) models.Money) Rule[T] {
+	return Rule[T]{Field: field, Name: "min", Severity: models.SeverityError, Test: func(v T) string {
+		if get(v) < 0 {
+			return field + " must be non-negative"
+		}
+		return ""
+	}}
+}
+
+// Check wraps a validator returning an error as a rule, for checks that don't fit the declarative forms.
+func Check[T any](field, name, severity string, validate func(T) error) Rule[T] {
+	return Rule[T]{Field: field, Name: name, Severity: severity, Test: func(v T) string {
+		if err := validate(v); err != nil {
+			return err.Error()
+		}
+		return ""
+	}}
+}
+
+// IssuesError joins the error-severity issues into a single error, or returns nil if there are none.
+func IssuesError(issues []models.Issue) error {
+	var messages []string
+	for _, issue := range issues {
+		if issue.Severity == models.SeverityError {
+			messages = append(messages, issue.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, ";\n"))
+}
+
+// plural returns word, with an s unless n is 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
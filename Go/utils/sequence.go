@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sequenceToken matches the tokens a number pattern may contain.
+var sequenceToken = regexp.MustCompile(`\{(YYYY|YY|MM|DD|SEQ(?::(\d+))?)\}`)
+
+// maxSequencePadding keeps generated numbers within a sensible length.
+const maxSequencePadding = 12
+
+// ValidateNumberPattern checks a number pattern: it must contain exactly one {SEQ} or {SEQ:n}
+// token and every other {...} must be a known date token.
+func ValidateNumberPattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("pattern is required")
+	}
+	if len(pattern) > 50 {
+		return errors.New("pattern must be at most 50 characters")
+	}
+
+	seqCount := 0
+	for _, match := range sequenceToken.FindAllStringSubmatch(pattern, -1) {
+		if !strings.HasPrefix(match[1], "SEQ") {
+			continue
+		}
+		seqCount++
+		if match[2] != "" {
+			if width, _ := strconv.Atoi(match[2]); width < 1 || width > maxSequencePadding {
+				return fmt.Errorf("{SEQ:n} padding must be between 1 and %d", maxSequencePadding)
+			}
+		}
+	}
+	if seqCount != 1 {
+		return errors.New("pattern must contain exactly one {SEQ} or {SEQ:n} token")
+	}
+
+	// Anything left in braces once the known tokens are removed is a typo
+	if rest := sequenceToken.ReplaceAllString(pattern, ""); strings.ContainsAny(rest, "{}") {
+		return errors.New("pattern contains an unknown token; use {YYYY}, {YY}, {MM}, {DD} and {SEQ:n}")
+	}
+	return nil
+}
+
+// SequencePeriod returns the period the counter of a pattern runs in on date, built from the
+// date tokens the pattern uses: INV-{YYYY}{MM}-{SEQ:5} restarts monthly and has periods like
+// "2025-01". A pattern without date tokens never restarts and has the period "".
+func SequencePeriod(pattern string, date time.Time) string {
+	var year, month, day bool
+	for _, match := range sequenceToken.FindAllStringSubmatch(pattern, -1) {
+		switch match[1] {
+		case "YYYY", "YY":
+			year = true
+		case "MM":
+			month = true
+		case "DD":
+			day = true
+		}
+	}
+
+	var parts []string
+	if year {
+		parts = append(parts, date.Format("2006"))
+	}
+	if month {
+		parts = append(parts, date.Format("01"))
+	}
+	if day {
+		parts = append(parts, date.Format("02"))
+	}
+	return strings.Join(parts, "-")
+}
+
+// FormatSequenceNumber fills in the tokens of a pattern for date and the counter value.
+func FormatSequenceNumber(pattern string, date time.Time, value int64) string {
+	return sequenceToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		match := sequenceToken.FindStringSubmatch(token)
+		switch match[1] {
+		case "YYYY":
+			return date.Format("2006")
+		case "YY":
+			return date.Format("06")
+		case "MM":
+			return date.Format("01")
+		case "DD":
+			return date.Format("02")
+		}
+		width, _ := strconv.Atoi(match[2])
+		return fmt.Sprintf("%0*d", width, value)
+	})
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"widatech-technical-challenge/internal/models"
+)
+
+// invoiceStatusTransitions lists the statuses each invoice status can move to.
+var invoiceStatusTransitions = map[string][]string{
+	models.InvoiceStatusDraft:  {models.InvoiceStatusIssued, models.InvoiceStatusVoid},
+	models.InvoiceStatusIssued: {models.InvoiceStatusVoid},
+	models.InvoiceStatusVoid:   {},
+}
+
+// ValidateInvoiceStatus ensures the status a new invoice is created with is draft or issued.
+// An empty status is accepted and treated as draft.
+func ValidateInvoiceStatus(status string) error {
+	switch status {
+	case "", models.InvoiceStatusDraft, models.InvoiceStatusIssued:
+		return nil
+	}
+	return fmt.Errorf("invalid status: new invoices must be '%s' or '%s'", models.InvoiceStatusDraft, models.InvoiceStatusIssued)
+}
+
+// ValidateStatusTransition ensures an invoice may move from one status to another.
+func ValidateStatusTransition(from, to string) error {
+	for _, allowed := range invoiceStatusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot change invoice status from '%s' to '%s'", from, to)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"unicode/utf8"
+	"widatech-technical-challenge/internal/models"
+)
+
+// ValidateTaxMode ensures the tax mode is one of the supported pricing modes.
+// An empty mode is accepted and treated as exclusive.
+func ValidateTaxMode(mode string) error {
+	switch mode {
+	case "", models.TaxModeExclusive, models.TaxModeInclusive:
+		return nil
+	}
+	return errors.New("invalid tax mode: must be 'exclusive' or 'inclusive'")
+}
+
+// ValidateTaxRate checks the fields of a tax rate before it is stored.
+func ValidateTaxRate(taxRate models.TaxRate) error {
+	if utf8.RuneCountInString(taxRate.Code) < 1 {
+		return errors.New("code must have at least 1 character")
+	}
+	if utf8.RuneCountInString(taxRate.Name) < 2 {
+		return errors.New("name must have at least 2 characters")
+	}
+	if taxRate.Rate < 0 || taxRate.Rate > 10000 {
+		return errors.New("rate must be between 0 and 100")
+	}
+	return nil
+}
+
+// ApplyProductTax records the rate on the product and computes its tax amount.
+// In exclusive mode the tax is added on top of total_price; in inclusive mode
+// it is the part of total_price that is tax.
+func ApplyProductTax(product *models.Product, rate models.Percent, mode string) {
+	product.TaxRate = rate
+	if mode == models.TaxModeInclusive {
+		product.TaxAmount = product.TotalPrice.MulRatio(int64(rate), 10000+int64(rate))
+		return
+	}
+	product.TaxAmount = product.TotalPrice.Percent(rate)
+}
+
+// TaxableAmount returns the product's price excluding tax.
+func TaxableAmount(product models.Product, mode string) models.Money {
+	if mode == models.TaxModeInclusive {
+		return product.TotalPrice.Sub(product.TaxAmount)
+	}
+	return product.TotalPrice
+}
@@ -2,68 +2,144 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 	"widatech-technical-challenge/internal/models"
 )
 
-func ValidateInvoiceFields(invoice models.Invoice) error {
-	var validationErrors []string
+var paymentMethodCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_-]*$`)
 
-	// Validate individual fields and append error messages.
-	if len(invoice.InvoiceNo) < 1 {
-		validationErrors = append(validationErrors, "invoice_no must have at least 1 character")
+// invoiceRules are the checks on an invoice's own fields. The length and range rules mirror the
+// CHECK constraints of initial.sql and the later migrations, so bad data is reported before it
+// reaches the database, whether it came from the API or an import.
+func invoiceRules(paymentMethods map[string]bool) RuleSet[models.Invoice] {
+	return RuleSet[models.Invoice]{
+		MinLength("invoice_no", 1, func(i models.Invoice) string { return i.InvoiceNo }),
+		Check("date", "required", models.SeverityError, func(i models.Invoice) error {
+			if i.Date.IsZero() {
+				return errors.New("date is required")
+			}
+			return nil
+		}),
+		MinLength("customer_name", 2, func(i models.Invoice) string { return i.CustomerName }),
+		MinLength("salesperson_name", 2, func(i models.Invoice) string { return i.SalespersonName }),
+		Check("payment_type", "one_of", models.SeverityError, func(i models.Invoice) error {
+			return ValidateInvoicePaymentType(i.PaymentType, paymentMethods)
+		}),
+		Check("currency", "format", models.SeverityError, func(i models.Invoice) error {
+			if i.Currency == "" {
+				return nil
+			}
+			return ValidateCurrency(i.Currency)
+		}),
+		Check("status", "one_of", models.SeverityError, func(i models.Invoice) error { return ValidateInvoiceStatus(i.Status) }),
+		Check("tax_mode", "one_of", models.SeverityError, func(i models.Invoice) error { return ValidateTaxMode(i.TaxMode) }),
+		Check("due_date", "not_before_date", models.SeverityError, func(i models.Invoice) error {
+			if i.DueDate != nil && DaysBetween(i.Date, *i.DueDate) < 0 {
+				return errors.New("due_date cannot be before date")
+			}
+			return nil
+		}),
+		Check("payment_terms_days", "min", models.SeverityError, func(i models.Invoice) error {
+			if i.PaymentTerms != nil && *i.PaymentTerms < 0 {
+				return errors.New("payment_terms_days must be non-negative")
+			}
+			return nil
+		}),
+		OptionalMinLength("notes", 5, func(i models.Invoice) string { return i.Notes }),
+		Check("products", "required", models.SeverityError, func(i models.Invoice) error {
+			if len(i.Products) == 0 {
+				return errors.New("products list cannot be empty")
+			}
+			return nil
+		}),
 	}
-	if invoice.Date.IsZero() {
-		validationErrors = append(validationErrors, "date is required")
-	}
-	if len(invoice.CustomerName) < 2 {
-		validationErrors = append(validationErrors, "customer_name must have at least 2 characters")
-	}
-	if len(invoice.SalespersonName) < 2 {
-		validationErrors = append(validationErrors, "salesperson_name must have at least 2 characters")
-	}
-	if err := ValidateInvoicePaymentType(invoice); err != nil {
-		validationErrors = append(validationErrors, err.Error())
+}
+
+// productRules are the checks on a product line, mirroring the CHECK constraints of the products table.
+// Each reports under the field it is about, so an import points at the column to fix.
+var productRules = RuleSet[models.Product]{
+	MinLength("item_name", 5, func(p models.Product) string { return p.ItemName }),
+	MinInt("quantity", 1, func(p models.Product) int { return p.Quantity }),
+	NonNegative("total_cost", func(p models.Product) models.Money { return p.TotalCost }),
+	NonNegative("total_price", func(p models.Product) models.Money { return p.TotalPrice }),
+	NonNegative("unit_cost", func(p models.Product) models.Money { return p.UnitCost }),
+	NonNegative("unit_price", func(p models.Product) models.Money { return p.UnitPrice }),
+	NonNegative("discount_amount", func(p models.Product) models.Money { return p.DiscountAmount }),
+	Check("discount_percent", "range", models.SeverityError, validateDiscountPercent),
+	Check("discount_amount", "pricing", models.SeverityError, validateProductDiscount),
+	Check("total_cost", "pricing", models.SeverityError, validateProductCost),
+	Check("total_price", "pricing", models.SeverityError, validateProductPrice),
+}
+
+// ValidateInvoice evaluates the invoice and product rules, accepting the payment methods in paymentMethods,
+// along with the warning rules; strict mode reports the chosen warnings as errors.
+// Product issues are reported against products[i]. Product pricing must be resolved first.
+func ValidateInvoice(invoice models.Invoice, paymentMethods map[string]bool) []models.Issue {
+	config := LoadWarningConfig()
+	issues := append(invoiceRules(paymentMethods).Evaluate(invoice), invoiceWarningRules(config).Evaluate(invoice)...)
+	for i, product := range invoice.Products {
+		productIssues := append(productRules.Evaluate(product), productWarningRules(config).Evaluate(product)...)
+		for _, issue := range productIssues {
+			issue.Field = fmt.Sprintf("products[%d].%s", i, issue.Field)
+			issue.Message = fmt.Sprintf("products[%d]: %s", i, issue.Message)
+			issues = append(issues, issue)
+		}
 	}
-	if invoice.Notes != "" && len(invoice.Notes) < 5 {
-		validationErrors = append(validationErrors, "notes must have at least 5 characters if provided")
+	return config.escalate(issues)
+}
+
+// ValidateInvoiceFields checks the invoice fields, accepting the payment methods in paymentMethods.
+func ValidateInvoiceFields(invoice models.Invoice, paymentMethods map[string]bool) error {
+	return IssuesError(invoiceRules(paymentMethods).Evaluate(invoice))
+}
+
+// ValidateInvoiceUpdateFields evaluates the invoice rules on the text fields an invoice update provides,
+// holding them to the lengths they are created with. Fields left blank are not changed, so not checked.
+func ValidateInvoiceUpdateFields(update models.UpdateInvoiceRequest) []models.Issue {
+	provided := map[string]bool{
+		"customer_name":    update.CustomerName != "",
+		"salesperson_name": update.SalespersonName != "",
+		"notes":            update.Notes != "",
 	}
-	if len(invoice.Products) == 0 {
-		validationErrors = append(validationErrors, "products list cannot be empty")
+	fields := models.Invoice{CustomerName: update.CustomerName, SalespersonName: update.SalespersonName, Notes: update.Notes}
+	var issues []models.Issue
+	for _, issue := range invoiceRules(nil).Evaluate(fields) {
+		if provided[issue.Field] {
+			issues = append(issues, issue)
+		}
 	}
+	return issues
+}
 
-	// If there are validation errors, join them into a single string with newlines and return.
-	if len(validationErrors) > 0 {
-		return errors.New(strings.Join(validationErrors, ";\n"))
+// ValidateInvoicePaymentType ensures that the payment type is one of the active payment methods.
+func ValidateInvoicePaymentType(paymentType string, paymentMethods map[string]bool) error {
+	if !paymentMethods[paymentType] {
+		codes := make([]string, 0, len(paymentMethods))
+		for code := range paymentMethods {
+			codes = append(codes, "'"+code+"'")
+		}
+		sort.Strings(codes)
+		return fmt.Errorf("invalid payment type: must be one of %s", strings.Join(codes, ", "))
 	}
 	return nil
 }
 
-// ValidateInvoicePaymentType ensures that the PaymentType of an Invoice is valid.
-func ValidateInvoicePaymentType(invoice models.Invoice) error {
-	validPaymentTypes := map[string]bool{
-		"CASH":   true,
-		"CREDIT": true,
+// ValidatePaymentMethod checks the fields of a payment method before it is stored.
+func ValidatePaymentMethod(paymentMethod models.PaymentMethod) error {
+	if !paymentMethodCodePattern.MatchString(paymentMethod.Code) {
+		return errors.New("code must be upper case letters, digits, '-' or '_', starting with a letter")
 	}
-	if !validPaymentTypes[invoice.PaymentType] {
-		return errors.New("invalid payment type: must be 'CASH' or 'CREDIT'")
+	if utf8.RuneCountInString(paymentMethod.Name) < 2 {
+		return errors.New("name must have at least 2 characters")
 	}
 	return nil
 }
 
-// validateProduct checks that the product meets the specified requirements.
+// ValidateProduct checks that the product meets the specified requirements.
 func ValidateProduct(product models.Product) error {
-	if len(product.ItemName) < 5 {
-		return errors.New("item_name must have at least 5 characters")
-	}
-	if product.Quantity < 1 {
-		return errors.New("quantity must be at least 1")
-	}
-	if product.TotalCost < 0 {
-		return errors.New("total_cost must be non-negative")
-	}
-	if product.TotalPrice < 0 {
-		return errors.New("total_price must be non-negative")
-	}
-	return nil
+	return IssuesError(productRules.Evaluate(product))
 }
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+// The cases below break one rule each, labelled with the column it guards. That the database rejects
+// the same values is checked against the migrated schema by TestRulesMatchSchemaChecks in the
+// repository package. Lengths are counted in characters, as PostgreSQL's LENGTH does, so "É" is one
+// character although it takes two bytes.
+
+// validInvoice returns an invoice that passes every rule, for the cases to break one field of.
+func validInvoice() models.Invoice {
+	terms := 30
+	return models.Invoice{
+		InvoiceNo:       "INV-001",
+		Date:            time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		CustomerName:    "John",
+		SalespersonName: "Doe",
+		PaymentType:     "CASH",
+		Notes:           "Lorem ipsum",
+		Currency:        "IDR",
+		TaxMode:         models.TaxModeExclusive,
+		Status:          models.InvoiceStatusDraft,
+		PaymentTerms:    &terms,
+		Products: []models.Product{{
+			ItemName:   "Bluetooth speaker",
+			Quantity:   2,
+			UnitCost:   5000,
+			UnitPrice:  7500,
+			TotalCost:  10000,
+			TotalPrice: 15000,
+		}},
+	}
+}
+
+func TestValidateInvoiceChecks(t *testing.T) {
+	tests := []struct {
+		check   string // Column and rule the case breaks
+		change  func(i *models.Invoice)
+		field   string // Field of the expected error, "" if the invoice passes
+		message string // Part of the expected message
+	}{
+		// initial.sql
+		{"invoices.invoice_no minLength 1", func(i *models.Invoice) { i.InvoiceNo = "" }, "invoice_no", "at least 1"},
+		{"invoices.customer_name LENGTH >= 2", func(i *models.Invoice) { i.CustomerName = "J" }, "customer_name", "at least 2"},
+		{"invoices.customer_name LENGTH >= 2, multibyte", func(i *models.Invoice) { i.CustomerName = "É" }, "customer_name", "at least 2"},
+		{"invoices.customer_name LENGTH >= 2, multibyte passes", func(i *models.Invoice) { i.CustomerName = "Éa" }, "", ""},
+		{"invoices.salesperson_name LENGTH >= 2", func(i *models.Invoice) { i.SalespersonName = "É" }, "salesperson_name", "at least 2"},
+		{"invoices.chk_notes_length", func(i *models.Invoice) { i.Notes = "Noté" }, "notes", "at least 5"},
+		{"invoices.chk_notes_length, notes omitted", func(i *models.Invoice) { i.Notes = "" }, "", ""},
+		{"products.item_name LENGTH >= 5", func(i *models.Invoice) { i.Products[0].ItemName = "Café" }, "products[0].item_name", "at least 5"},
+		{"products.quantity >= 1", func(i *models.Invoice) { i.Products[0].Quantity = 0 }, "products[0].quantity", "at least 1"},
+		{"products.total_cost >= 0", func(i *models.Invoice) { i.Products[0].TotalCost = -1 }, "products[0].total_cost", "non-negative"},
+		{"products.total_price >= 0", func(i *models.Invoice) { i.Products[0].TotalPrice = -1 }, "products[0].total_price", "non-negative"},
+
+		// v002_product_unit_prices.sql
+		{"products.unit_cost >= 0", func(i *models.Invoice) { i.Products[0].UnitCost = -1 }, "products[0].unit_cost", "unit_cost must be non-negative"},
+		{"products.unit_price >= 0", func(i *models.Invoice) { i.Products[0].UnitPrice = -1 }, "products[0].unit_price", "unit_price must be non-negative"},
+		{"products.discount_amount >= 0", func(i *models.Invoice) { i.Products[0].DiscountAmount = -1 }, "products[0].discount_amount", "discount_amount must be non-negative"},
+		{"products.discount_percent BETWEEN 0 AND 100", func(i *models.Invoice) { i.Products[0].DiscountPercent = 10001 }, "products[0].discount_percent", "between 0 and 100"},
+
+		// Pricing, checked across fields and reported under the field that disagrees
+		{"products discount above quantity × unit_price", func(i *models.Invoice) { i.Products[0].DiscountAmount = 20000 }, "products[0].discount_amount", "cannot exceed"},
+		{"products total_cost = quantity × unit_cost", func(i *models.Invoice) { i.Products[0].TotalCost = 9000 }, "products[0].total_cost", "quantity × unit_cost"},
+		{"products total_price = quantity × unit_price − discount", func(i *models.Invoice) { i.Products[0].TotalPrice = 14000 }, "products[0].total_price", "quantity × unit_price"},
+
+		// v003_tax_rates.sql
+		{"invoices.tax_mode IN ('exclusive', 'inclusive')", func(i *models.Invoice) { i.TaxMode = "gross" }, "tax_mode", "invalid tax mode"},
+
+		// v004_currencies.sql
+		{"invoices.currency ~ '^[A-Z]{3}$'", func(i *models.Invoice) { i.Currency = "usd" }, "currency", "invalid currency"},
+
+		// v006_payments.sql
+		{"invoices.payment_terms_days >= 0", func(i *models.Invoice) { *i.PaymentTerms = -1 }, "payment_terms_days", "non-negative"},
+		{"invoices.chk_due_date", func(i *models.Invoice) {
+			dueDate := i.Date.AddDate(0, 0, -1)
+			i.DueDate = &dueDate
+		}, "due_date", "before date"},
+
+		// v007_invoice_status.sql
+		{"invoices.status IN ('draft', 'issued', 'void')", func(i *models.Invoice) { i.Status = "paid" }, "status", "invalid status"},
+	}
+	paymentMethods := map[string]bool{"CASH": true}
+	for _, tt := range tests {
+		invoice := validInvoice()
+		tt.change(&invoice)
+		var errs []models.Issue
+		for _, issue := range ValidateInvoice(invoice, paymentMethods) {
+			if issue.Severity == models.SeverityError {
+				errs = append(errs, issue)
+			}
+		}
+		if tt.field == "" {
+			if len(errs) > 0 {
+				t.Errorf("%s: got %v, want no errors", tt.check, errs)
+			}
+			continue
+		}
+		found := false
+		for _, issue := range errs {
+			if issue.Field == tt.field && strings.Contains(issue.Message, tt.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: got %v, want an error on %s containing %q", tt.check, errs, tt.field, tt.message)
+		}
+	}
+}
+
+func TestValidateRecordChecks(t *testing.T) {
+	rateDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	balance := models.InvoiceBalance{OutstandingAmount: 100000}
+	tests := []struct {
+		check   string // Column and rule the case breaks
+		err     error
+		message string // Part of the expected message, "" if the value passes
+	}{
+		// v003_tax_rates.sql
+		{"tax_rates.code LENGTH >= 1", ValidateTaxRate(models.TaxRate{Code: "", Name: "PPN", Rate: 1100}), "code"},
+		{"tax_rates.name LENGTH >= 2", ValidateTaxRate(models.TaxRate{Code: "PPN", Name: "É", Rate: 1100}), "name"},
+		{"tax_rates.name LENGTH >= 2, multibyte passes", ValidateTaxRate(models.TaxRate{Code: "PPN", Name: "Év", Rate: 1100}), ""},
+		{"tax_rates.rate BETWEEN 0 AND 100", ValidateTaxRate(models.TaxRate{Code: "PPN", Name: "PPN", Rate: 10001}), "between 0 and 100"},
+
+		// v004_currencies.sql
+		{"exchange_rates.currency ~ '^[A-Z]{3}$'", ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "US", RateDate: rateDate, Rate: models.RateOne}), "invalid currency"},
+		{"exchange_rates.rate > 0", ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "USD", RateDate: rateDate}), "greater than 0"},
+		{"exchange_rates.chk_exchange_rates_pair", ValidateExchangeRate(models.ExchangeRate{BaseCurrency: "IDR", Currency: "IDR", RateDate: rateDate, Rate: models.RateOne}), "differ"},
+
+		// v005_payment_methods.sql
+		{"payment_methods.code ~ '^[A-Z][A-Z0-9_-]*$'", ValidatePaymentMethod(models.PaymentMethod{Code: "cash", Name: "Cash"}), "code"},
+		{"payment_methods.name LENGTH >= 2", ValidatePaymentMethod(models.PaymentMethod{Code: "CASH", Name: "É"}), "name"},
+
+		// v006_payments.sql
+		{"payments.amount > 0", ValidatePayment(models.Payment{Amount: 0, PaidAt: rateDate}, balance, rateDate), "greater than 0"},
+
+		// v008_credit_notes.sql
+		{"credit_note_lines.quantity >= 1", ProrateCreditNoteLine(&models.CreditNoteLine{ProductID: 1}, models.Product{Quantity: 2}, models.CreditNoteLine{}), "at least 1"},
+	}
+	for _, tt := range tests {
+		switch {
+		case tt.message == "" && tt.err != nil:
+			t.Errorf("%s: got %v, want no error", tt.check, tt.err)
+		case tt.message != "" && (tt.err == nil || !strings.Contains(tt.err.Error(), tt.message)):
+			t.Errorf("%s: got %v, want an error containing %q", tt.check, tt.err, tt.message)
+		}
+	}
+}
+
+func TestValidateInvoiceUpdateFields(t *testing.T) {
+	tests := []struct {
+		update models.UpdateInvoiceRequest
+		fields []string // Fields of the expected errors
+	}{
+		{models.UpdateInvoiceRequest{PaymentType: "CASH"}, nil},
+		{models.UpdateInvoiceRequest{CustomerName: "É"}, []string{"customer_name"}},
+		{models.UpdateInvoiceRequest{CustomerName: "Éa", SalespersonName: "D"}, []string{"salesperson_name"}},
+		{models.UpdateInvoiceRequest{Notes: "Noté"}, []string{"notes"}},
+		{models.UpdateInvoiceRequest{CustomerName: "J", Notes: "Lorem ipsum"}, []string{"customer_name"}},
+	}
+	for _, tt := range tests {
+		var fields []string
+		for _, issue := range ValidateInvoiceUpdateFields(tt.update) {
+			fields = append(fields, issue.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("ValidateInvoiceUpdateFields(%+v) reported %v, want %v", tt.update, fields, tt.fields)
+		}
+	}
+}
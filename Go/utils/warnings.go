@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"widatech-technical-challenge/internal/models"
+)
+
+// Names of the warning rules, as listed in STRICT_WARNINGS.
+const (
+	WarningBelowCost     = "below_cost"
+	WarningZeroPrice     = "zero_price"
+	WarningFutureDate    = "future_date"
+	WarningLargeQuantity = "large_quantity"
+	WarningDuplicateItem = "duplicate_item"
+)
+
+// Defaults used when the WARN_* environment variables are not set.
+const (
+	defaultWarnMaxQuantity    = 10000
+	defaultWarnFutureDateDays = 0
+)
+
+// WarningConfig holds the thresholds of the warning rules and which of them strict mode turns into errors.
+type WarningConfig struct {
+	MaxQuantity    int             // Quantities above this are flagged as large_quantity
+	FutureDateDays int             // Dates more than this many days after today are flagged as future_date
+	Strict         map[string]bool // Warning rules reported as errors instead
+}
+
+// LoadWarningConfig reads the warning thresholds from WARN_MAX_QUANTITY and WARN_FUTURE_DATE_DAYS,
+// and the strict rules from STRICT_WARNINGS, a comma-separated list of rule names or "all".
+func LoadWarningConfig() WarningConfig {
+	config := WarningConfig{MaxQuantity: defaultWarnMaxQuantity, FutureDateDays: defaultWarnFutureDateDays, Strict: map[string]bool{}}
+	if n, err := strconv.Atoi(os.Getenv("WARN_MAX_QUANTITY")); err == nil && n > 0 {
+		config.MaxQuantity = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("WARN_FUTURE_DATE_DAYS")); err == nil && n >= 0 {
+		config.FutureDateDays = n
+	}
+	for _, name := range strings.Split(os.Getenv("STRICT_WARNINGS"), ",") {
+		if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+			config.Strict[name] = true
+		}
+	}
+	return config
+}
+
+// escalate reports the warnings strict mode covers as errors.
+func (c WarningConfig) escalate(issues []models.Issue) []models.Issue {
+	for i := range issues {
+		if issues[i].Severity == models.SeverityWarning && (c.Strict["all"] || c.Strict[issues[i].Rule]) {
+			issues[i].Severity = models.SeverityError
+		}
+	}
+	return issues
+}
+
+// invoiceWarningRules flag invoices that are valid but unusual enough to be a mistake.
+func invoiceWarningRules(config WarningConfig) RuleSet[models.Invoice] {
+	return RuleSet[models.Invoice]{
+		futureDateRule(config, func(i models.Invoice) time.Time { return i.Date }),
+		Rule[models.Invoice]{Field: "products", Name: WarningDuplicateItem, Severity: models.SeverityWarning, Test: func(i models.Invoice) string {
+			return duplicateItems(i.Products)
+		}},
+	}
+}
+
+// productWarningRules flag product lines that are valid but unusual enough to be a mistake.
+func productWarningRules(config WarningConfig) RuleSet[models.Product] {
+	return RuleSet[models.Product]{
+		Rule[models.Product]{Field: "total_price", Name: WarningBelowCost, Severity: models.SeverityWarning, Test: func(p models.Product) string {
+			if p.TotalPrice < p.TotalCost {
+				return fmt.Sprintf("total_price %s is below total_cost %s", p.TotalPrice, p.TotalCost)
+			}
+			return ""
+		}},
+		Rule[models.Product]{Field: "total_price", Name: WarningZeroPrice, Severity: models.SeverityWarning, Test: func(p models.Product) string {
+			if p.TotalPrice == 0 {
+				return "total_price is zero"
+			}
+			return ""
+		}},
+		Rule[models.Product]{Field: "quantity", Name: WarningLargeQuantity, Severity: models.SeverityWarning, Test: func(p models.Product) string {
+			if p.Quantity > config.MaxQuantity {
+				return fmt.Sprintf("quantity %d is above %d", p.Quantity, config.MaxQuantity)
+			}
+			return ""
+		}},
+	}
+}
+
+// futureDateRule flags a date more than config.FutureDateDays after today.
+func futureDateRule[T any](config WarningConfig, get func(T) time.Time) Rule[T] {
+	return Rule[T]{Field: "date", Name: WarningFutureDate, Severity: models.SeverityWarning, Test: func(v T) string {
+		date := get(v)
+		if date.IsZero() {
+			return ""
+		}
+		if days := DaysBetween(time.Now(), date); days > config.FutureDateDays {
+			return fmt.Sprintf("date %s is %d days in the future", date.Format("2006-01-02"), days)
+		}
+		return ""
+	}}
+}
+
+// duplicateItems describes the item names that appear on more than one line, or returns "".
+func duplicateItems(products []models.Product) string {
+	seen := map[string]bool{}
+	var duplicates []string
+	for _, product := range products {
+		key := strings.ToLower(strings.TrimSpace(product.ItemName))
+		if seen[key] && key != "" {
+			duplicates = append(duplicates, fmt.Sprintf("%q", strings.TrimSpace(product.ItemName)))
+		}
+		seen[key] = true
+	}
+	if len(duplicates) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s appears on more than one line", strings.Join(duplicates, ", "))
+}
+
+// ValidateProductChange evaluates the rules for a product added to or changed on an invoice
+// whose lines, including the change, are lines.
+func ValidateProductChange(product models.Product, lines []models.Product) []models.Issue {
+	config := LoadWarningConfig()
+	issues := append(productRules.Evaluate(product), productWarningRules(config).Evaluate(product)...)
+	if message := duplicateItems(lines); message != "" {
+		issues = append(issues, models.Issue{Field: "products", Rule: WarningDuplicateItem, Severity: models.SeverityWarning, Message: message})
+	}
+	return config.escalate(issues)
+}
+
+// ValidateInvoiceUpdate evaluates the warning rules that apply to the fields of an invoice update.
+func ValidateInvoiceUpdate(update models.UpdateInvoiceRequest) []models.Issue {
+	config := LoadWarningConfig()
+	rules := RuleSet[models.UpdateInvoiceRequest]{
+		futureDateRule(config, func(u models.UpdateInvoiceRequest) time.Time { return u.Date }),
+	}
+	return config.escalate(rules.Evaluate(update))
+}
+
+// Warnings returns the warning-severity issues.
+func Warnings(issues []models.Issue) []models.Issue {
+	warnings := []models.Issue{}
+	for _, issue := range issues {
+		if issue.Severity == models.SeverityWarning {
+			warnings = append(warnings, issue)
+		}
+	}
+	return warnings
+}